@@ -19,6 +19,7 @@ function pack() {
   echo "Signing ${OUTPUT_BINARY}"
 	mkdir ${PACKAGE_DIR}
 	cp -r ./htdocs ${PACKAGE_DIR}
+	cp -r ./conf ${PACKAGE_DIR}
 
 	md5sum ${OUTPUT_BINARY} | sed 's/\ .*\// /g' >> ${PACKAGE_DIR}/md5.hash
 	sha1sum ${OUTPUT_BINARY} | sed 's/\ .*\// /g' >> ${PACKAGE_DIR}/sha1.hash
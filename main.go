@@ -3,6 +3,8 @@ package main
 import (
 	"./server"
 	"./utils/config"
+	"./utils/counter"
+	"./utils/registry"
 	"flag"
 	"fmt"
 	_ "github.com/elazarl/go-bindata-assetfs"
@@ -16,6 +18,17 @@ func main() {
 	c.AddFlags(flag.CommandLine)
 
 	sv := flag.Bool("version", false, "Show version and exit")
+	vt := flag.String("view-token", "", "Show the view token of the given section (username/repository) and exit")
+	reg := flag.String("register", "", "Register the given section (username/repository) or owner (username) and exit")
+	unreg := flag.String("unregister", "", "Unregister the given section (username/repository) or owner (username) and exit")
+	lreg := flag.Bool("registered", false, "List all registered sections and owners and exit")
+	rename := flag.String("rename", "", "Rename the given section (username/repository) to the section given by -to and exit; the server has to be stopped")
+	merge := flag.String("merge", "", "Merge the given section (username/repository) into the section given by -to and exit; the server has to be stopped")
+	alias := flag.String("alias", "", "Redirect the given section (username/repository) to the section given by -to and exit")
+	unalias := flag.String("unalias", "", "Remove the alias of the given section (username/repository) and exit")
+	laliases := flag.Bool("aliases", false, "List all aliases and exit")
+	to := flag.String("to", "", "Target section (username/repository) of -rename, -merge and -alias")
+	redirect := flag.Bool("redirect", false, "Redirect the renamed or merged section to its target")
 	flag.Parse()
 
 	c.Build = config.Build{
@@ -31,6 +44,89 @@ func main() {
 
 	c.Load(c.File)
 
+	if *vt != "" {
+		if c.ViewSecret == "" {
+			fmt.Println("No view secret configured")
+			return
+		}
+		fmt.Printf("View token of %s: %s\n", *vt, server.ViewToken(c.ViewSecret, *vt))
+		return
+	}
+
+	if *reg != "" || *unreg != "" || *lreg {
+		r := registry.NewRegistry(c.RegistryFile)
+		if *reg != "" {
+			if err := r.Register(*reg); err != nil {
+				fmt.Println(err)
+				return
+			}
+		}
+		if *unreg != "" && !r.Unregister(*unreg) {
+			fmt.Printf("%s isn't registered\n", *unreg)
+			return
+		}
+		if *reg != "" || *unreg != "" {
+			if err := r.Save(); err != nil {
+				fmt.Println(err)
+				return
+			}
+		}
+		fmt.Println(r.Entries().String())
+		return
+	}
+
+	if *rename != "" || *merge != "" {
+		store, err := counter.NewStore(c.Storage, c.StorageFile)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		cnt := counter.NewCounterWithStore(c.SessionLifetime, store)
+		from := *rename
+		operation := cnt.Rename
+		if *merge != "" {
+			from = *merge
+			operation = cnt.Merge
+		}
+		section, err := operation(from, *to)
+		if err != nil {
+			_ = cnt.Close()
+			fmt.Println(err)
+			return
+		}
+		if err := cnt.Close(); err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Println(section.String())
+		if !*redirect {
+			return
+		}
+		*alias = from
+	}
+
+	if *alias != "" || *unalias != "" || *laliases {
+		a := registry.NewAliases(c.AliasesFile)
+		if *alias != "" {
+			if err := a.Set(*alias, *to); err != nil {
+				fmt.Println(err)
+				return
+			}
+		}
+		if *unalias != "" && !a.Remove(*unalias) {
+			fmt.Printf("%s has no alias\n", *unalias)
+			return
+		}
+		if *alias != "" || *unalias != "" {
+			if err := a.Save(); err != nil {
+				fmt.Println(err)
+				return
+			}
+		}
+		fmt.Println(a.Entries().String())
+		return
+	}
+
 	if c.SaveConfigFlag {
 		if _, err := c.Save(); err != nil {
 			print(err)
@@ -0,0 +1,221 @@
+package server
+
+import (
+	"../utils/counter"
+	"../utils/log"
+	"crypto/subtle"
+	"github.com/go-web/httpmux"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// admin only passes requests carrying the configured admin token as bearer
+// token to the given writer
+func (s *Server) admin(writer writerFunc) writerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if s.Config.AdminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.Config.AdminToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		writer(w, r)
+	}
+}
+
+func (s *Server) registryResponse(w http.ResponseWriter, r *http.Request) {
+	if s.Registry == nil {
+		http.Error(w, "registration mode disabled", http.StatusNotFound)
+		return
+	}
+	writeOutput(w, r, s.Registry.Entries())
+}
+
+func (s *Server) registerResponse(w http.ResponseWriter, r *http.Request) {
+	if s.Registry == nil {
+		http.Error(w, "registration mode disabled", http.StatusNotFound)
+		return
+	}
+	if err := s.Registry.Register(registryKey(r)); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.saveRegistry(w, r)
+}
+
+func (s *Server) unregisterResponse(w http.ResponseWriter, r *http.Request) {
+	if s.Registry == nil {
+		http.Error(w, "registration mode disabled", http.StatusNotFound)
+		return
+	}
+	if !s.Registry.Unregister(registryKey(r)) {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	s.saveRegistry(w, r)
+}
+
+func (s *Server) saveRegistry(w http.ResponseWriter, r *http.Request) {
+	if err := s.Registry.Save(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeOutput(w, r, s.Registry.Entries())
+}
+
+// registryKey returns the registry key addressed by the request, either an
+// owner (username) or a section (username/repository)
+func registryKey(r *http.Request) string {
+	key := sanitize(httpmux.Params(r).ByName("username"))
+	if repository := httpmux.Params(r).ByName("repository"); repository != "" {
+		key += "/" + sanitize(repository)
+	}
+	return key
+}
+
+// renameResponse renames the requested section to the section given by the
+// to query parameter. If redirect is true, an alias is created which keeps
+// counting hits of the old section into the renamed one.
+func (s *Server) renameResponse(w http.ResponseWriter, r *http.Request) {
+	s.sectionOperation(w, r, s.Counter.Rename)
+}
+
+// mergeResponse merges the requested section into the section given by the
+// to query parameter
+func (s *Server) mergeResponse(w http.ResponseWriter, r *http.Request) {
+	s.sectionOperation(w, r, s.Counter.Merge)
+}
+
+func (s *Server) sectionOperation(w http.ResponseWriter, r *http.Request, operation func(from string, to string) (*counter.Section, error)) {
+	query := r.URL.Query()
+	from := registryKey(r)
+	to := query.Get("to")
+
+	section, err := operation(from, to)
+	if err != nil {
+		http.Error(w, err.Error(), sectionErrorStatus(err))
+		return
+	}
+
+	if s.Registry != nil && s.Registry.Unregister(from) {
+		_ = s.Registry.Register(to)
+		if err := s.Registry.Save(); err != nil {
+			log.Error(err)
+		}
+	}
+	if query.Get("redirect") == "true" {
+		if err := s.Aliases.Set(from, to); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := s.Aliases.Save(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	writeOutput(w, r, section.GetStats(""))
+}
+
+// setTotalResponse sets the total of the requested section to the value
+// given by the total query parameter. The section is created if necessary.
+func (s *Server) setTotalResponse(w http.ResponseWriter, r *http.Request) {
+	total, err := strconv.ParseInt(r.URL.Query().Get("total"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid total", http.StatusBadRequest)
+		return
+	}
+	s.updateSection(w, r, true, func(section *counter.Section) error {
+		return section.SetTotal(total)
+	})
+}
+
+// adjustTotalResponse adds the value given by the delta query parameter to
+// the total of the requested section. Negative values are subtracted.
+func (s *Server) adjustTotalResponse(w http.ResponseWriter, r *http.Request) {
+	delta, err := strconv.ParseInt(r.URL.Query().Get("delta"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid delta", http.StatusBadRequest)
+		return
+	}
+	s.updateSection(w, r, false, func(section *counter.Section) error {
+		return section.AdjustTotal(delta)
+	})
+}
+
+func (s *Server) resetResponse(w http.ResponseWriter, r *http.Request) {
+	s.updateSection(w, r, false, func(section *counter.Section) error {
+		return section.Reset()
+	})
+}
+
+// updateSection applies the given update to the loaded section of the
+// requested repository and saves it right away, so the change can't be
+// overwritten by a stale copy
+func (s *Server) updateSection(w http.ResponseWriter, r *http.Request, create bool, update func(section *counter.Section) error) {
+	username, repository, err := counter.ParseKey(registryKey(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !create && !s.Counter.HasSection(username+"/"+repository) {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	section, err := s.Counter.Update(s.Counter.GetSection(username, repository), update)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := section.Save(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.activities <- section
+
+	writeOutput(w, r, section.GetStats(""))
+}
+
+func (s *Server) aliasesResponse(w http.ResponseWriter, r *http.Request) {
+	writeOutput(w, r, s.Aliases.Entries())
+}
+
+func (s *Server) setAliasResponse(w http.ResponseWriter, r *http.Request) {
+	if err := s.Aliases.Set(registryKey(r), r.URL.Query().Get("to")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.saveAliases(w, r)
+}
+
+func (s *Server) removeAliasResponse(w http.ResponseWriter, r *http.Request) {
+	if !s.Aliases.Remove(registryKey(r)) {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	s.saveAliases(w, r)
+}
+
+func (s *Server) saveAliases(w http.ResponseWriter, r *http.Request) {
+	if err := s.Aliases.Save(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeOutput(w, r, s.Aliases.Entries())
+}
+
+// sectionErrorStatus maps the errors of section operations to status codes
+func sectionErrorStatus(err error) int {
+	switch err {
+	case counter.ErrSectionNotFound:
+		return http.StatusNotFound
+	case counter.ErrSectionExists:
+		return http.StatusConflict
+	case counter.ErrInvalidKey, counter.ErrSameSection:
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
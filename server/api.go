@@ -1,8 +1,12 @@
 package server
 
 import (
+	"../utils/badge"
 	"../utils/counter"
-	"crypto/sha256"
+	"../utils/geoip"
+	"../utils/log"
+	"../utils/metrics"
+	"../utils/useragent"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
@@ -11,10 +15,18 @@ import (
 	"net"
 	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
 
+const (
+	// Maximum number of path segments of a page
+	MaxPageDepth = 8
+	// Maximum length of a page path
+	MaxPageLength = 256
+)
+
 func (s *Server) handleRequest(writer writerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		writer(w, r)
@@ -26,7 +38,80 @@ func (s *Server) indexResponse(r *http.Request) interface{} {
 }
 
 func (s *Server) jsonResponse(w http.ResponseWriter, r *http.Request) {
-	content, err := json.MarshalIndent(s.getSection(r), "", "\t")
+	s.writeStats(w, r, s.getStats(r), "json")
+}
+
+func (s *Server) xmlResponse(w http.ResponseWriter, r *http.Request) {
+	s.writeStats(w, r, s.getStats(r), "xml")
+}
+
+func (s *Server) csvResponse(w http.ResponseWriter, r *http.Request) {
+	s.writeStats(w, r, s.getStats(r), "csv")
+}
+
+// getStats returns the requested section including the value of the metric
+// requested by the metric query parameter
+func (s *Server) getStats(r *http.Request) *counter.Stats {
+	section := s.getSection(r)
+
+	stats := section.GetStats(r.URL.Query().Get("metric"))
+	stats.Rollup = s.Counter.GetRollup(section)
+	return stats
+}
+
+func (s *Server) historyResponse(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	granularity := query.Get("granularity")
+
+	to := parseTime(query.Get("to"), time.Now())
+	from := to.Add(-30 * 24 * time.Hour)
+	if granularity == counter.GranularityHour {
+		from = to.Add(-24 * time.Hour)
+	}
+	from = parseTime(query.Get("from"), from)
+
+	username, repository, page := s.sectionParams(r)
+	report := s.Counter.GetHistory(username, repository, page, from, to, granularity)
+
+	writeOutput(w, r, report)
+}
+
+func (s *Server) referrersResponse(w http.ResponseWriter, r *http.Request) {
+	section := s.getSection(r)
+	report := section.GetReferrers()
+
+	writeOutput(w, r, report)
+}
+
+func (s *Server) geoResponse(w http.ResponseWriter, r *http.Request) {
+	section := s.getSection(r)
+	report := section.GetCountries()
+
+	writeOutput(w, r, report)
+}
+
+func (s *Server) clientsResponse(w http.ResponseWriter, r *http.Request) {
+	section := s.getSection(r)
+	report := section.GetClients()
+
+	writeOutput(w, r, report)
+}
+
+// writeOutput encodes the given value in the format requested by the output
+// query parameter (json, xml or csv). JSON is used by default.
+func writeOutput(w http.ResponseWriter, r *http.Request, v fmt.Stringer) {
+	switch strings.ToLower(r.URL.Query().Get("output")) {
+	case "xml":
+		writeXML(w, v)
+	case "csv":
+		writeCSV(w, v)
+	default:
+		writeJSON(w, v)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	content, err := json.MarshalIndent(v, "", "\t")
 	if err != nil {
 		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
 		return
@@ -39,12 +124,12 @@ func (s *Server) jsonResponse(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (s *Server) xmlResponse(w http.ResponseWriter, r *http.Request) {
+func writeXML(w http.ResponseWriter, v interface{}) {
 	w.Header().Set("Content-Type", "application/xml")
 
 	x := xml.NewEncoder(w)
 	x.Indent("", "\t")
-	if err := x.Encode(s.getSection(r)); err != nil {
+	if err := x.Encode(v); err != nil {
 		fmt.Println(err)
 		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
 		return
@@ -55,9 +140,51 @@ func (s *Server) xmlResponse(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (s *Server) csvResponse(w http.ResponseWriter, r *http.Request) {
+func writeCSV(w http.ResponseWriter, v fmt.Stringer) {
 	w.Header().Set("Content-Type", "text/csv")
-	if n, err := io.WriteString(w, s.getSection(r).String()); err != nil || n <= 0 {
+	if n, err := io.WriteString(w, v.String()); err != nil || n <= 0 {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+}
+
+// ShieldsEndpoint is the response expected by the shields.io endpoint badge
+// https://shields.io/endpoint
+type ShieldsEndpoint struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Label         string `json:"label"`
+	Message       string `json:"message"`
+	Color         string `json:"color"`
+	CacheSeconds  int    `json:"cacheSeconds,omitempty"`
+}
+
+func (s *Server) shieldsResponse(w http.ResponseWriter, r *http.Request) {
+	var section *counter.Section
+	if s.Config.ShieldsCount {
+		section = s.hit(w, r)
+	} else {
+		section = s.getSection(r)
+	}
+
+	s.writeShields(w, s.newBadge(r, section))
+}
+
+// writeShields writes the given badge as shields.io endpoint response
+func (s *Server) writeShields(w http.ResponseWriter, b *badge.Badge) {
+	content, err := json.Marshal(&ShieldsEndpoint{
+		SchemaVersion: 1,
+		Label:         b.Label,
+		Message:       b.Text(),
+		Color:         strings.TrimPrefix(b.Color, "#"),
+		CacheSeconds:  int(s.Config.ShieldsCacheLifetime.Seconds()),
+	})
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if n, err := w.Write(content); err != nil || n <= 0 {
 		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
 		return
 	}
@@ -66,76 +193,204 @@ func (s *Server) csvResponse(w http.ResponseWriter, r *http.Request) {
 func (s *Server) badgeResponse(w http.ResponseWriter, r *http.Request) {
 
 	SetHeaders(w)
-	counterStr := s.count(r)
-	badge := fmt.Sprintf(`<?xml version="1.0"?>
-	<svg xmlns="http://www.w3.org/2000/svg" width="80" height="20">
-		<rect width="30" height="20" fill="#555"/>
-		<rect x="30" width="50" height="20" fill="#4c1"/>
-	
-		<rect rx="3" width="80" height="20" fill="transparent"/>
-		<g fill="#fff" text-anchor="middle" font-family="DejaVu Sans,Verdana,Geneva,sans-serif" font-size="11">
-			<text x="15" y="14">hits</text>
-			<text x="54" y="14">%s</text>
-		</g>
-		<!-- If you have time to help us add descriptive comments please PR! -->
-	</svg>`, counterStr)
-
-	if n, err := io.WriteString(w, badge); err != nil || n <= 0 {
+	s.writeSVG(w, s.newBadge(r, s.hit(w, r)))
+}
+
+// writeSVG renders the given badge using the badge templates
+func (s *Server) writeSVG(w http.ResponseWriter, b *badge.Badge) {
+	content, err := b.Render(s.badges)
+	if err != nil {
+		log.Error(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	if n, err := io.WriteString(w, content); err != nil || n <= 0 {
 		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
 		return
 	}
 }
 
+func (s *Server) metricsResponse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	if _, err := metrics.Default.WriteTo(w); err != nil {
+		log.Error(err)
+	}
+}
+
 func (s *Server) badgeHeadResponse(w http.ResponseWriter, r *http.Request) {
 	SetHeaders(w)
-	_ = s.count(r)
+	_ = s.hit(w, r)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) pngResponse(w http.ResponseWriter, r *http.Request) {
+	writePNG(w, r, s.newBadge(r, s.hit(w, r)))
+}
+
+// writePNG renders the given badge as PNG, scaled by the scale query
+// parameter
+func writePNG(w http.ResponseWriter, r *http.Request, b *badge.Badge) {
+	scale, _ := strconv.Atoi(r.URL.Query().Get("scale"))
+
+	content, err := b.PNG(scale)
+	if err != nil {
+		log.Error(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	SetImageHeaders(w, "image/png")
+	w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+	if n, err := w.Write(content); err != nil || n <= 0 {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+}
+
+func (s *Server) pngHeadResponse(w http.ResponseWriter, r *http.Request) {
+	SetImageHeaders(w, "image/png")
+	_ = s.hit(w, r)
 	w.WriteHeader(http.StatusOK)
 }
 
 func (s *Server) getSection(r *http.Request) *counter.Section {
+	username, repository, page := s.sectionParams(r)
+
+	return s.Counter.GetPage(username, repository, page)
+}
+
+// sectionParams returns the username, repository and page of the requested
+// section. Aliases of the repository are resolved to the repository they
+// point to, pages are kept.
+func (s *Server) sectionParams(r *http.Request) (string, string, string) {
 	username := sanitize(httpmux.Params(r).ByName("username"))
 	repository := sanitize(httpmux.Params(r).ByName("repository"))
+	page := sanitizePage(httpmux.Params(r).ByName("page"))
 
-	return s.Counter.GetSection(username, repository)
+	if s.Aliases != nil {
+		if u, rp, err := counter.ParseKey(s.Aliases.Resolve(username + "/" + repository)); err == nil {
+			return u, rp, page
+		}
+	}
+	return username, repository, page
 }
 
-func (s *Server) count(r *http.Request) string {
+// newBadge creates a badge displaying the metric requested by the metric
+// query parameter. All other badge options are applied afterwards.
+func (s *Server) newBadge(r *http.Request, section *counter.Section) *badge.Badge {
+	query := r.URL.Query()
+	metric := counter.ParseMetric(query.Get("metric"))
+
+	value := section.GetMetric(metric)
+
+	b := badge.NewBadge(s.formatNumber(r, value))
+	b.Label = counter.MetricLabels[metric]
+	return b.Parse(query)
+}
+
+// hit counts the request as a hit if it belongs to a new visitor and returns
+// the affected section. View only requests aren't counted.
+func (s *Server) hit(w http.ResponseWriter, r *http.Request) *counter.Section {
 
 	section := s.getSection(r)
+	if s.isViewOnly(r, section.GetKey()) {
+		return section
+	}
+
 	userAgent := r.Header.Get("User-Agent")
-	if strings.Contains(userAgent, "camo"){
-		// Treat camouflaged request as new hit - is likely cached anyways
-		s.mx.Lock()
-		section.Increment()
-		s.mx.Unlock()
-		s.activities <- section
-	}else{
-		host, _, _ := net.SplitHostPort(r.RemoteAddr)
-		h := sha256.New()
-		h.Write([]byte(host + userAgent))
-		token := fmt.Sprintf("%x", h.Sum(nil))
+	if s.Bots != nil && s.Bots.IsBot(userAgent) {
+		s.Counter.IncrementBot(section)
+		return section
+	}
+	sectionKey := section.GetKey()
+	decision, client := s.Proxy.Decide(r, sectionKey)
+
+	counted := false
+	switch decision {
+	case ProxyDecisionCount:
+		s.Counter.Increment(section)
+		counted = true
+	case ProxyDecisionWeighted:
+		if n := s.Proxy.Weigh(sectionKey); n > 0 {
+			s.Counter.IncrementBy(section, n)
+			counted = true
+		} else {
+			decision = ProxyDecisionSkipped
+		}
+	default:
+		token := s.hasher.Hash(s.getIdentifier(sectionKey).Identify(w, client))
 
 		entry := counter.NewEntry(token)
+		counted = s.Counter.AddEntry(section, entry)
+	}
 
-		s.mx.Lock()
-		if s.Counter.AddEntry(section, entry) {
-			s.activities <- section
+	if counted {
+		country := s.country(decision, client)
+
+		var ua *useragent.Client
+		if s.Clients != nil {
+			ua = s.Clients.Parse(userAgent)
+		}
+
+		s.Counter.AddReferrer(section, counter.ParseReferrer(r.Header.Get("Referer"), s.Config.ReferrerPaths), s.Config.ReferrerLimit)
+		s.Counter.AddCountry(section, country)
+		if ua != nil {
+			s.Counter.AddClient(section, ua.Browser, ua.OS, ua.Device)
 		}
-		s.mx.Unlock()
+		s.activities <- section
+	}
+	if decision != ProxyDecisionNone {
+		metrics.Add(`proxy_requests_total{decision="`+decision+`"}`, 1)
 	}
 
-	total := float64(section.Total)
+	return section
+}
 
-	counterStr := fmt.Sprintf("%.0f", total)
-	if total > 1000000 {
-		counterStr = fmt.Sprintf("%.2fm", total/1000000)
-	} else if total > 10000 {
-		counterStr = fmt.Sprintf("%.0fk", total/1000)
-	} else if total > 1000 {
-		counterStr = fmt.Sprintf("%.2fk", total/1000)
+// country returns the country of the visitor or an empty string if GeoIP
+// lookups are disabled. Image proxies which don't pass the original client
+// would be located instead of the visitor, hence their country is unknown.
+// Only the resulting country code is kept, the address itself is never stored.
+func (s *Server) country(decision string, client *http.Request) string {
+	if s.Geo == nil {
+		return ""
+	}
+	switch decision {
+	case ProxyDecisionCount, ProxyDecisionWeighted, ProxyDecisionDedupe:
+		return geoip.Unknown
+	}
+	return s.Geo.Country(net.ParseIP(remoteHost(client)))
+}
+
+// formatNumber formats the given number using the server wide number format
+// unless a different one was requested by the format, precision or locale
+// query parameters
+func (s *Server) formatNumber(r *http.Request, n int64) string {
+	query := r.URL.Query()
+	precision, err := strconv.Atoi(query.Get("precision"))
+	if err != nil {
+		precision = -1
 	}
 
-	return counterStr
+	return s.Formatter.With(query.Get("format"), precision, query.Get("locale")).Format(n)
+}
+
+// parseTime parses a RFC 3339 timestamp, a date (2006-01-02) or a unix
+// timestamp. The fallback is returned for empty or invalid values.
+func parseTime(in string, fallback time.Time) time.Time {
+	if in == "" {
+		return fallback
+	}
+	if t, err := time.Parse(time.RFC3339, in); err == nil {
+		return t
+	}
+	if t, err := time.Parse("2006-01-02", in); err == nil {
+		return t
+	}
+	if n, err := strconv.ParseInt(in, 10, 64); err == nil {
+		return time.Unix(n, 0)
+	}
+	return fallback
 }
 
 func sanitize(in string) string {
@@ -143,8 +398,35 @@ func sanitize(in string) string {
 	return reg.ReplaceAllString(in, "_")
 }
 
+// sanitizePage sanitizes every segment of the given page path. Empty and
+// relative segments are dropped and the path is limited to MaxPageDepth
+// segments and MaxPageLength characters.
+func sanitizePage(in string) string {
+	var segments []string
+	for _, segment := range strings.Split(in, "/") {
+		segment = sanitize(segment)
+		if strings.Trim(segment, ".") == "" {
+			continue
+		}
+		segments = append(segments, segment)
+		if len(segments) == MaxPageDepth {
+			break
+		}
+	}
+
+	page := strings.Join(segments, "/")
+	if len(page) > MaxPageLength {
+		page = strings.Trim(page[:MaxPageLength], "/")
+	}
+	return page
+}
+
 func SetHeaders(w http.ResponseWriter) {
-	w.Header().Set("Content-Type", "image/svg+xml;charset=utf-8")
+	SetImageHeaders(w, "image/svg+xml;charset=utf-8")
+}
+
+func SetImageHeaders(w http.ResponseWriter, contentType string) {
+	w.Header().Set("Content-Type", contentType)
 	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate, max-age=60, s-maxage=60")
 
 	loc, _ := time.LoadLocation("UTC")
@@ -29,18 +29,57 @@ func (s *Server) NewHandler() (http.Handler, error) {
 	})
 	mux.HandleFunc("HEAD", "/", func(w http.ResponseWriter, req *http.Request) {})
 
-	mux.GET("/svg/:username/:repository", s.registerHandler(s.badgeResponse))
-	mux.HEAD("/svg/:username/:repository", s.registerHandler(s.badgeHeadResponse))
+	handleSection(mux.GET, "/svg", s.registerHandler(s.registered(s.badgeResponse, s.unregisteredBadgeResponse)))
+	handleSection(mux.HEAD, "/svg", s.registerHandler(s.registered(s.badgeHeadResponse, s.unregisteredBadgeHeadResponse)))
 
-	mux.GET("/json/:username/:repository", s.registerHandler(s.jsonResponse))
-	mux.GET("/xml/:username/:repository", s.registerHandler(s.xmlResponse))
-	mux.GET("/csv/:username/:repository", s.registerHandler(s.csvResponse))
+	handleSection(mux.GET, "/png", s.registerHandler(s.registered(s.pngResponse, s.unregisteredPNGResponse)))
+	handleSection(mux.HEAD, "/png", s.registerHandler(s.registered(s.pngHeadResponse, s.unregisteredPNGHeadResponse)))
+
+	handleSection(mux.GET, "/json", s.registerHandler(s.registered(s.jsonResponse, notFoundResponse)))
+	handleSection(mux.GET, "/xml", s.registerHandler(s.registered(s.xmlResponse, notFoundResponse)))
+	handleSection(mux.GET, "/csv", s.registerHandler(s.registered(s.csvResponse, notFoundResponse)))
+
+	handleSection(mux.GET, "/shields", s.registerHandler(s.registered(s.shieldsResponse, s.unregisteredShieldsResponse)))
+
+	handleSection(mux.GET, "/history", s.registerHandler(s.registered(s.historyResponse, notFoundResponse)))
+	handleSection(mux.GET, "/referrers", s.registerHandler(s.registered(s.referrersResponse, notFoundResponse)))
+	handleSection(mux.GET, "/geo", s.registerHandler(s.registered(s.geoResponse, notFoundResponse)))
+	handleSection(mux.GET, "/clients", s.registerHandler(s.registered(s.clientsResponse, notFoundResponse)))
+
+	if s.Config.Metrics {
+		mux.GET("/metrics", s.registerHandler(s.metricsResponse))
+	}
+
+	if s.Config.AdminToken != "" {
+		mux.GET("/admin/registry", s.handleRequest(s.admin(s.registryResponse)))
+		mux.PUT("/admin/registry/owners/:username", s.handleRequest(s.admin(s.registerResponse)))
+		mux.DELETE("/admin/registry/owners/:username", s.handleRequest(s.admin(s.unregisterResponse)))
+		mux.PUT("/admin/registry/sections/:username/:repository", s.handleRequest(s.admin(s.registerResponse)))
+		mux.DELETE("/admin/registry/sections/:username/:repository", s.handleRequest(s.admin(s.unregisterResponse)))
+
+		mux.PUT("/admin/sections/:username/:repository/total", s.handleRequest(s.admin(s.setTotalResponse)))
+		mux.POST("/admin/sections/:username/:repository/total", s.handleRequest(s.admin(s.adjustTotalResponse)))
+		mux.DELETE("/admin/sections/:username/:repository", s.handleRequest(s.admin(s.resetResponse)))
+		mux.POST("/admin/sections/:username/:repository/rename", s.handleRequest(s.admin(s.renameResponse)))
+		mux.POST("/admin/sections/:username/:repository/merge", s.handleRequest(s.admin(s.mergeResponse)))
+
+		mux.GET("/admin/aliases", s.handleRequest(s.admin(s.aliasesResponse)))
+		mux.PUT("/admin/aliases/:username/:repository", s.handleRequest(s.admin(s.setAliasResponse)))
+		mux.DELETE("/admin/aliases/:username/:repository", s.handleRequest(s.admin(s.removeAliasResponse)))
+	}
 
 	mux.GET("/ws", s.registerSocketHandler())
 
 	return mux, nil
 }
 
+// handleSection registers the given handler for a repository and all pages
+// below it, e.g. /svg/:username/:repository/docs/install
+func handleSection(register func(pattern string, f http.HandlerFunc), prefix string, f http.HandlerFunc) {
+	register(prefix+"/:username/:repository", f)
+	register(prefix+"/:username/:repository/*page", f)
+}
+
 func (s *Server) registerHandler(writer writerFunc) http.HandlerFunc {
 	return s.Api.cors.Handler(s.handleRequest(writer)).ServeHTTP
 }
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"../utils/counter"
+	"bytes"
+	"crypto/sha1"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// writeStats encodes the given stats in the given representation (json, xml
+// or csv). The validators are derived from the encoded content, hence they
+// change whenever the output does.
+func (s *Server) writeStats(w http.ResponseWriter, r *http.Request, stats *counter.Stats, representation string) {
+	content, contentType, err := encodeStats(stats, representation)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	if s.writeValidators(w, r, statsETag(content), lastModified(stats, time.Now())) {
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	if n, err := w.Write(content); err != nil || n <= 0 {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+}
+
+func encodeStats(stats *counter.Stats, representation string) ([]byte, string, error) {
+	switch representation {
+	case "xml":
+		buf := &bytes.Buffer{}
+		x := xml.NewEncoder(buf)
+		x.Indent("", "\t")
+		if err := x.Encode(stats); err != nil {
+			return nil, "", err
+		}
+		buf.WriteByte('\n')
+		return buf.Bytes(), "application/xml", nil
+	case "csv":
+		return []byte(stats.String()), "text/csv", nil
+	}
+	content, err := json.MarshalIndent(stats, "", "\t")
+	return content, "application/json", err
+}
+
+// statsETag derives a weak entity tag from the encoded stats
+func statsETag(content []byte) string {
+	return fmt.Sprintf(`W/"%x"`, sha1.Sum(content))
+}
+
+// lastModified returns the time the given stats changed the last time,
+// including the sections of their roll-up. Metrics of a period change at
+// the start of each day, even if there haven't been any hits.
+func lastModified(stats *counter.Stats, now time.Time) time.Time {
+	modified := stats.UpdatedAt
+	if modified.IsZero() {
+		modified = stats.CreatedAt
+	}
+	if stats.Rollup != nil && stats.Rollup.UpdatedAt.After(modified) {
+		modified = stats.Rollup.UpdatedAt
+	}
+	if stats.Metric != "" && stats.Metric != counter.MetricTotal {
+		if day := now.UTC().Truncate(24 * time.Hour); day.After(modified) {
+			modified = day
+		}
+	}
+	return modified.UTC().Truncate(time.Second)
+}
+
+// writeValidators sets the caching headers of a stats response. A 304 Not
+// Modified response is written and true returned if the client already
+// holds the current representation.
+func (s *Server) writeValidators(w http.ResponseWriter, r *http.Request, etag string, modified time.Time) bool {
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+	if s.Config.StatsCacheControl != "" {
+		w.Header().Set("Cache-Control", s.Config.StatsCacheControl)
+	}
+
+	if notModified(r, etag, modified) {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+// notModified evaluates the If-None-Match and If-Modified-Since headers. The
+// latter is ignored if the first one is present (RFC 7232, section 6).
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, tag := range strings.Split(match, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	if since := r.Header.Get("If-Modified-Since"); since != "" {
+		if t, err := http.ParseTime(since); err == nil {
+			return !modified.After(t)
+		}
+	}
+	return false
+}
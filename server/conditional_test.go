@@ -0,0 +1,48 @@
+package server
+
+import (
+	"../utils/config"
+	"../utils/counter"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWriteStatsValidators(t *testing.T) {
+	s := &Server{Config: &config.Config{}}
+	section := counter.NewSection("user", "repository")
+	stats := section.GetStats("")
+	stats.Rollup = &counter.Rollup{Repository: 1, Owner: 1}
+
+	w := httptest.NewRecorder()
+	s.writeStats(w, httptest.NewRequest("GET", "/json/user/repository", nil), stats, "json")
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag == "" {
+		t.Fatalf("responded %d with etag %q", w.Code, etag)
+	}
+
+	r := httptest.NewRequest("GET", "/json/user/repository", nil)
+	r.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	s.writeStats(w, r, stats, "json")
+	if w.Code != http.StatusNotModified {
+		t.Errorf("responded %d to an unchanged output, expected 304", w.Code)
+	}
+
+	// A hit of a page only changes the roll-up
+	stats.Rollup = &counter.Rollup{Repository: 2, Owner: 2, UpdatedAt: time.Now().Add(time.Hour)}
+	w = httptest.NewRecorder()
+	s.writeStats(w, r, stats, "json")
+	if w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+		t.Errorf("responded %d with etag %q to a changed roll-up", w.Code, w.Header().Get("ETag"))
+	}
+
+	r = httptest.NewRequest("GET", "/json/user/repository", nil)
+	r.Header.Set("If-Modified-Since", time.Now().UTC().Format(http.TimeFormat))
+	w = httptest.NewRecorder()
+	s.writeStats(w, r, stats, "json")
+	if w.Code != http.StatusOK {
+		t.Errorf("responded %d to a roll-up changed after If-Modified-Since, expected 200", w.Code)
+	}
+}
@@ -4,7 +4,11 @@ import (
 	"../utils/config"
 	"../utils/counter"
 	"../utils/filesystem"
+	"../utils/geoip"
 	"../utils/log"
+	"../utils/number"
+	"../utils/registry"
+	"../utils/useragent"
 	assetfs "github.com/elazarl/go-bindata-assetfs"
 	"github.com/gorilla/websocket"
 	"github.com/rs/cors"
@@ -12,11 +16,14 @@ import (
 	olog "log"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"text/template"
+	"time"
 )
 
 // Create a custom visitor struct which holds the rate limiter for each
@@ -45,8 +52,21 @@ type Server struct {
 	mx        *sync.RWMutex
 
 	template *template.Template
+	badges   *template.Template
 	assets *assetfs.AssetFS
 
+	Formatter *number.Formatter
+	Bots      *useragent.BotClassifier
+	Clients   *useragent.Parser
+	Proxy     *ProxyPolicy
+	Geo       *geoip.Resolver
+	Registry  *registry.Registry
+	Aliases   *registry.Aliases
+
+	identifier         VisitorIdentifier
+	sectionIdentifiers map[string]VisitorIdentifier
+	hasher             VisitorHasher
+
 	Upgrader websocket.Upgrader
 
 	Api *ApiHandler
@@ -77,9 +97,14 @@ func NewServerConfig(c *config.Config, assets *assetfs.AssetFS) *Server {
 		c.PongWait = c.PingPeriod + defaultConfig.PongWait
 	}
 
+	store, err := counter.NewStore(c.Storage, c.StorageFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	s := &Server{
 		Config:  c,
-		Counter: counter.NewCounter(c.SessionLifetime),
+		Counter: counter.NewCounterWithStore(c.SessionLifetime, store),
 
 		Host: host,
 		Port: port,
@@ -100,12 +125,53 @@ func NewServerConfig(c *config.Config, assets *assetfs.AssetFS) *Server {
 		},
 
 		RateLimit: NewRateLimit(c.RateLimitLimit, c.RateLimitBurst, c.RateLimitInterval),
+		Formatter: number.NewFormatter(c.NumberFormat, c.NumberPrecision, c.NumberLocale),
 		Api:       &ApiHandler{},
 		mx:        &sync.RWMutex{},
 	}
 
 	s.assets = assets
-	s.template = ParseTemplates("htdocs/template")
+
+	s.Counter.Policy = &counter.Policy{
+		JunkMinHits:     c.JunkMinHits,
+		JunkAge:         c.JunkAge,
+		JunkDeleteFiles: c.JunkDeleteFiles,
+		IdleTTL:         c.IdleTTL,
+		MaxSections:     c.MaxSections,
+	}
+	s.Counter.FlushInterval = c.FlushInterval
+	s.Counter.FlushConcurrency = c.FlushConcurrency
+
+	s.identifier = NewVisitorIdentifier(c.VisitorIdentifier)
+	s.sectionIdentifiers = make(map[string]VisitorIdentifier)
+	for sectionKey, name := range c.SectionVisitorIdentifiers {
+		s.sectionIdentifiers[sectionKey] = NewVisitorIdentifier(name)
+	}
+	s.Proxy = NewProxyPolicy(c)
+	if c.BotFilter {
+		s.Bots = useragent.NewBotClassifier(c.BotPatternsFile, c.BotEmptyUserAgent)
+	}
+	if c.RegistrationMode {
+		s.Registry = registry.NewRegistry(c.RegistryFile)
+	}
+	s.Aliases = registry.NewAliases(c.AliasesFile)
+	if c.UserAgentRulesFile != "" {
+		s.Clients = useragent.NewParser(c.UserAgentRulesFile)
+	}
+	if c.GeoIPDatabase != "" {
+		if s.Geo, err = geoip.NewResolver(c.GeoIPDatabase); err != nil {
+			log.Error(err)
+		}
+	}
+	if c.PrivacyMode {
+		s.hasher = NewRotatingHasher(24 * time.Hour)
+	} else {
+		s.hasher = &SHA256Hasher{}
+	}
+	s.template = ParseTemplates("htdocs/template", "htdocs/template/badges")
+	if _, err := os.Stat("htdocs/template/badges"); err == nil {
+		s.badges = ParseTemplates("htdocs/template/badges")
+	}
 
 	c.LogOutput = os.Stdout
 
@@ -134,6 +200,7 @@ func (s *Server) listen() {
 		// Register new clients
 		case section := <-s.activities:
 			sectionKey := section.GetKey()
+			s.mx.RLock()
 			if subscribers, ok := s.subscriptions[sectionKey]; ok {
 				for client, state := range subscribers {
 					if state {
@@ -148,6 +215,7 @@ func (s *Server) listen() {
 					}
 				}
 			}
+			s.mx.RUnlock()
 		// Unregister an existing client
 		case client := <-s.unregister:
 			if _, ok := s.clients[client]; ok {
@@ -165,6 +233,16 @@ func (s *Server) Start() {
 		log.Fatal(err)
 	}
 	go s.Counter.Run()
+	if s.Bots != nil {
+		go s.Bots.Watch(10 * time.Second)
+	}
+	if s.Clients != nil {
+		go s.Clients.Watch(10 * time.Second)
+	}
+	if s.Registry != nil {
+		go s.Registry.Watch(10 * time.Second)
+	}
+	go s.Aliases.Watch(10 * time.Second)
 	go s.listen()
 	if s.Config.ServerAddr != "" {
 		go s.runServer(f)
@@ -172,12 +250,29 @@ func (s *Server) Start() {
 	if s.Config.TLSServerAddr != "" {
 		go s.runTLSServer(f)
 	}
-	select {}
+
+	// Save all pending changes before exiting
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	<-sig
+	log.Info("Shutting down, saving sections")
+	if err := s.Counter.Close(); err != nil {
+		log.Error(err)
+	}
 }
 
-func ParseTemplates(_path string) *template.Template {
+// ParseTemplates parses all templates found in the given directory, skipping
+// the excluded sub directories
+func ParseTemplates(_path string, exclude ...string) *template.Template {
 	tpl := template.New("")
 	err := filepath.Walk(_path, func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.IsDir() {
+			for _, dir := range exclude {
+				if filepath.Clean(dir) == filepath.Clean(path) {
+					return filepath.SkipDir
+				}
+			}
+		}
 		if strings.Contains(path, ".tmpl") {
 			_, err = tpl.ParseFiles(path)
 			if err != nil {
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// VisitorHasher turns a visitor identity into the hash stored by the counter
+type VisitorHasher interface {
+	Hash(identity string) string
+}
+
+// SHA256Hasher hashes visitor identities using plain sha256
+type SHA256Hasher struct{}
+
+func (h *SHA256Hasher) Hash(identity string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(identity)))
+}
+
+// RotatingHasher hashes visitor identities using a HMAC keyed by a random
+// secret. The secret only lives in memory and is replaced at the start of
+// every period (UTC), which makes hashes of different periods unlinkable.
+type RotatingHasher struct {
+	Period time.Duration
+
+	mx    sync.Mutex
+	key   []byte
+	start time.Time
+}
+
+func NewRotatingHasher(period time.Duration) *RotatingHasher {
+	return &RotatingHasher{
+		Period: period,
+	}
+}
+
+func (h *RotatingHasher) Hash(identity string) string {
+	mac := hmac.New(sha256.New, h.currentKey())
+	mac.Write([]byte(identity))
+	return fmt.Sprintf("%x", mac.Sum(nil))
+}
+
+// currentKey returns the key of the current period. The key of the previous
+// period is discarded as soon as a new period starts.
+func (h *RotatingHasher) currentKey() []byte {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	start := time.Now().UTC().Truncate(h.Period)
+	if h.key == nil || !start.Equal(h.start) {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			panic(err)
+		}
+		h.key = key
+		h.start = start
+	}
+	return h.key
+}
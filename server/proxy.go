@@ -0,0 +1,152 @@
+package server
+
+import (
+	"../utils/config"
+	"../utils/log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const (
+	ProxyModeDedupe = "dedupe"
+	ProxyModeCount  = "count"
+	ProxyModeWeight = "weight"
+
+	// Not a request of an image proxy
+	ProxyDecisionNone = "none"
+	// The user agent matches, but the request doesn't originate from a trusted network
+	ProxyDecisionUntrusted = "untrusted"
+	// The original client is known by the configured header and counted as usual
+	ProxyDecisionClient   = "client"
+	ProxyDecisionDedupe   = "dedupe"
+	ProxyDecisionCount    = "count"
+	ProxyDecisionWeighted = "weighted"
+	// The weight mode didn't accumulate a full hit yet
+	ProxyDecisionSkipped = "skipped"
+)
+
+// ProxyPolicy decides how requests of image proxies and CDNs (e.g. GitHub's
+// camo) are counted. These requests usually don't carry anything to identify
+// the original visitor by.
+type ProxyPolicy struct {
+	UserAgents []string
+	Networks   []*net.IPNet
+	Header     string
+	Mode       string
+	Weight     float64
+
+	mx *sync.Mutex
+	// Fractional hits per section carried over by the weight mode
+	carry map[string]float64
+}
+
+func NewProxyPolicy(c *config.Config) *ProxyPolicy {
+	p := &ProxyPolicy{
+		Header: c.ImageProxyHeader,
+		Mode:   c.ImageProxyMode,
+		Weight: c.ImageProxyWeight,
+		mx:     &sync.Mutex{},
+		carry:  make(map[string]float64),
+	}
+	for _, ua := range strings.Split(c.ImageProxyUserAgents, ",") {
+		if ua = strings.ToLower(strings.TrimSpace(ua)); ua != "" {
+			p.UserAgents = append(p.UserAgents, ua)
+		}
+	}
+	for _, cidr := range strings.Split(c.ImageProxyCIDRs, ",") {
+		if cidr = strings.TrimSpace(cidr); cidr == "" {
+			continue
+		}
+		if _, network, err := net.ParseCIDR(cidr); err == nil {
+			p.Networks = append(p.Networks, network)
+		} else {
+			log.Error("invalid image proxy cidr: ", cidr)
+		}
+	}
+	if p.Mode != ProxyModeCount && p.Mode != ProxyModeWeight {
+		p.Mode = ProxyModeDedupe
+	}
+	if len(p.UserAgents) > 0 && len(p.Networks) == 0 {
+		log.Info("No image proxy networks configured, image proxy requests are counted like any other request")
+	}
+	return p
+}
+
+// Decide returns how the given request should be counted and the request
+// used to identify the visitor. If the proxy passes the original client in
+// the configured header, the returned request carries its address instead.
+// Proxies are only trusted from the configured networks, since any client
+// can send their user agent or header.
+func (p *ProxyPolicy) Decide(r *http.Request, sectionKey string) (string, *http.Request) {
+	if !p.matchesUserAgent(r.Header.Get("User-Agent")) {
+		return ProxyDecisionNone, r
+	}
+	if !p.isTrusted(remoteHost(r)) {
+		return ProxyDecisionUntrusted, r
+	}
+
+	if p.Header != "" {
+		if ip := clientIP(r.Header.Get(p.Header)); ip != nil {
+			client := r.Clone(r.Context())
+			client.RemoteAddr = net.JoinHostPort(ip.String(), "0")
+			return ProxyDecisionClient, client
+		}
+	}
+
+	switch p.Mode {
+	case ProxyModeCount:
+		return ProxyDecisionCount, r
+	case ProxyModeWeight:
+		return ProxyDecisionWeighted, r
+	}
+	return ProxyDecisionDedupe, r
+}
+
+func (p *ProxyPolicy) matchesUserAgent(userAgent string) bool {
+	userAgent = strings.ToLower(userAgent)
+	for _, ua := range p.UserAgents {
+		if strings.Contains(userAgent, ua) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *ProxyPolicy) isTrusted(host string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, network := range p.Networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Weigh adds the configured weight to the hits carried by the given section
+// and takes all full hits out of it
+func (p *ProxyPolicy) Weigh(sectionKey string) int64 {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	carry := p.carry[sectionKey] + p.Weight
+	n := int64(carry)
+	if carry -= float64(n); carry > 0 {
+		p.carry[sectionKey] = carry
+	} else {
+		delete(p.carry, sectionKey)
+	}
+	return n
+}
+
+// clientIP returns the first address of a header like X-Forwarded-For
+func clientIP(header string) net.IP {
+	if header == "" {
+		return nil
+	}
+	return net.ParseIP(strings.TrimSpace(strings.Split(header, ",")[0]))
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"../utils/config"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProxyDecide(t *testing.T) {
+	c := config.DefaultConfig()
+	c.ImageProxyHeader = "X-Forwarded-For"
+	untrusted := NewProxyPolicy(c)
+	c.ImageProxyCIDRs = "192.0.2.0/24"
+	trusted := NewProxyPolicy(c)
+	c.ImageProxyMode = ProxyModeCount
+	counting := NewProxyPolicy(c)
+
+	tests := []struct {
+		policy     *ProxyPolicy
+		remoteAddr string
+		userAgent  string
+		header     string
+		decision   string
+	}{
+		{trusted, "192.0.2.1:1234", "Mozilla/5.0", "", ProxyDecisionNone},
+		{trusted, "192.0.2.1:1234", "github-camo (876de43e)", "", ProxyDecisionDedupe},
+		{counting, "192.0.2.1:1234", "github-camo (876de43e)", "", ProxyDecisionCount},
+		{trusted, "192.0.2.1:1234", "github-camo (876de43e)", "198.51.100.7", ProxyDecisionClient},
+		{trusted, "198.51.100.1:1234", "github-camo (876de43e)", "198.51.100.7", ProxyDecisionUntrusted},
+		{untrusted, "192.0.2.1:1234", "github-camo (876de43e)", "198.51.100.7", ProxyDecisionUntrusted},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/svg/user/repository", nil)
+		r.RemoteAddr = test.remoteAddr
+		r.Header.Set("User-Agent", test.userAgent)
+		if test.header != "" {
+			r.Header.Set("X-Forwarded-For", test.header)
+		}
+		decision, client := test.policy.Decide(r, "user/repository")
+		if decision != test.decision {
+			t.Errorf("decided %s for %s (%s), expected %s", decision, test.remoteAddr, test.userAgent, test.decision)
+		}
+		if decision != ProxyDecisionClient && client.RemoteAddr != test.remoteAddr {
+			t.Errorf("identified %s as %s", test.remoteAddr, client.RemoteAddr)
+		}
+	}
+}
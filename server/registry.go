@@ -0,0 +1,74 @@
+package server
+
+import (
+	"../utils/badge"
+	"../utils/counter"
+	"net/http"
+)
+
+// registered passes requests of registered sections to the given writer and
+// all other requests to the fallback, without creating the section. Pages of
+// a registered repository are only created up to the configured limit. All
+// requests are passed to the writer if the registration mode is disabled.
+func (s *Server) registered(writer writerFunc, fallback writerFunc) writerFunc {
+	if s.Registry == nil {
+		return writer
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, repository, page := s.sectionParams(r)
+		if s.Registry.IsRegistered(username, repository) && s.allowsPage(username, repository, page) {
+			writer(w, r)
+			return
+		}
+		fallback(w, r)
+	}
+}
+
+// allowsPage reports whether the given page exists or may be created, since
+// its repository has less pages than allowed
+func (s *Server) allowsPage(username string, repository string, page string) bool {
+	if page == "" || s.Config.RegistrationMaxPages <= 0 {
+		return true
+	}
+	if s.Counter.Index.Has(username, repository, page) {
+		return true
+	}
+	return s.Counter.Index.Count(counter.SectionKey(username, repository, "")) < s.Config.RegistrationMaxPages
+}
+
+// unregisteredBadge creates the badge displayed for sections which aren't
+// registered
+func (s *Server) unregisteredBadge(r *http.Request) *badge.Badge {
+	b := badge.NewBadge("not registered").Parse(r.URL.Query())
+	b.Prefix = ""
+	b.Suffix = ""
+	b.Color, _ = badge.ParseColor("lightgrey")
+	return b
+}
+
+func (s *Server) unregisteredBadgeResponse(w http.ResponseWriter, r *http.Request) {
+	SetHeaders(w)
+	s.writeSVG(w, s.unregisteredBadge(r))
+}
+
+func (s *Server) unregisteredBadgeHeadResponse(w http.ResponseWriter, r *http.Request) {
+	SetHeaders(w)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) unregisteredPNGResponse(w http.ResponseWriter, r *http.Request) {
+	writePNG(w, r, s.unregisteredBadge(r))
+}
+
+func (s *Server) unregisteredPNGHeadResponse(w http.ResponseWriter, r *http.Request) {
+	SetImageHeaders(w, "image/png")
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) unregisteredShieldsResponse(w http.ResponseWriter, r *http.Request) {
+	s.writeShields(w, s.unregisteredBadge(r))
+}
+
+func notFoundResponse(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+}
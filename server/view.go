@@ -0,0 +1,28 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+)
+
+// ViewToken returns the token which allows to display the badge of the
+// given section without counting a hit
+func ViewToken(secret string, sectionKey string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(sectionKey))
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// isViewOnly reports whether the request asks not to be counted and carries
+// a valid view token for the given section. View only requests are disabled
+// as long as no view secret is configured.
+func (s *Server) isViewOnly(r *http.Request, sectionKey string) bool {
+	query := r.URL.Query()
+	if s.Config.ViewSecret == "" || query.Get("count") != "false" {
+		return false
+	}
+	token := query.Get("token")
+	return hmac.Equal([]byte(token), []byte(ViewToken(s.Config.ViewSecret, sectionKey)))
+}
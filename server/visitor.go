@@ -0,0 +1,118 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	IdentifierIP       = "ip"
+	IdentifierIPUA     = "ip-ua"
+	IdentifierPrefix   = "prefix"
+	IdentifierCookie   = "cookie"
+	DefaultIdentifier  = IdentifierIPUA
+	VisitorCookieName  = "gohits_visitor"
+	visitorCookieBytes = 16
+)
+
+// VisitorIdentifier identifies the visitor a request belongs to. The returned
+// identity is hashed before it is used to detect repeated hits.
+type VisitorIdentifier interface {
+	Identify(w http.ResponseWriter, r *http.Request) string
+}
+
+// NewVisitorIdentifier returns the identifier registered under the given
+// name or the default identifier (ip-ua) if the name is unknown
+func NewVisitorIdentifier(name string) VisitorIdentifier {
+	switch name {
+	case IdentifierIP:
+		return &IPIdentifier{}
+	case IdentifierPrefix:
+		return &PrefixIdentifier{IPv4Bits: 24, IPv6Bits: 64}
+	case IdentifierCookie:
+		return &CookieIdentifier{Lifetime: 365 * 24 * time.Hour}
+	}
+	return &IPUAIdentifier{}
+}
+
+// IPIdentifier identifies visitors by their ip address only
+type IPIdentifier struct{}
+
+func (i *IPIdentifier) Identify(w http.ResponseWriter, r *http.Request) string {
+	return remoteHost(r)
+}
+
+// IPUAIdentifier identifies visitors by their ip address and user agent
+type IPUAIdentifier struct{}
+
+func (i *IPUAIdentifier) Identify(w http.ResponseWriter, r *http.Request) string {
+	return remoteHost(r) + r.Header.Get("User-Agent")
+}
+
+// PrefixIdentifier identifies visitors by the network prefix of their ip
+// address and their user agent. Clients rotating their address within their
+// network (e.g. IPv6 privacy extensions) are therefore counted once.
+type PrefixIdentifier struct {
+	IPv4Bits int
+	IPv6Bits int
+}
+
+func (i *PrefixIdentifier) Identify(w http.ResponseWriter, r *http.Request) string {
+	host := remoteHost(r)
+	if ip := net.ParseIP(host); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			host = ip4.Mask(net.CIDRMask(i.IPv4Bits, 32)).String()
+		} else {
+			host = ip.Mask(net.CIDRMask(i.IPv6Bits, 128)).String()
+		}
+	}
+	return host + r.Header.Get("User-Agent")
+}
+
+// CookieIdentifier identifies visitors by a random id stored in a first
+// party cookie. Visitors without the cookie are issued a new one.
+type CookieIdentifier struct {
+	Lifetime time.Duration
+}
+
+func (i *CookieIdentifier) Identify(w http.ResponseWriter, r *http.Request) string {
+	if c, err := r.Cookie(VisitorCookieName); err == nil && c.Value != "" {
+		return "cookie:" + c.Value
+	}
+
+	b := make([]byte, visitorCookieBytes)
+	if _, err := rand.Read(b); err != nil {
+		return remoteHost(r) + r.Header.Get("User-Agent")
+	}
+	id := hex.EncodeToString(b)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     VisitorCookieName,
+		Value:    id,
+		Path:     "/",
+		Expires:  time.Now().Add(i.Lifetime),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return "cookie:" + id
+}
+
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// getIdentifier returns the visitor identifier configured for the given
+// section or the server wide one
+func (s *Server) getIdentifier(sectionKey string) VisitorIdentifier {
+	if identifier, ok := s.sectionIdentifiers[sectionKey]; ok {
+		return identifier
+	}
+	return s.identifier
+}
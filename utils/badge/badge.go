@@ -0,0 +1,91 @@
+package badge
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	StyleFlat        = "flat"
+	StyleFlatSquare  = "flat-square"
+	StylePlastic     = "plastic"
+	StyleForTheBadge = "for-the-badge"
+
+	DefaultLabel      = "hits"
+	DefaultColor      = "#4c1"
+	DefaultLabelColor = "#555"
+	// The original badge had square corners and no gradient
+	DefaultStyle = StyleFlatSquare
+
+	// Maximum number of characters accepted for any user supplied text
+	maxTextLength = 64
+)
+
+var themeName = regexp.MustCompile("^[a-zA-Z0-9_-]{1,64}$")
+
+var styles = map[string]bool{
+	StyleFlat:        true,
+	StyleFlatSquare:  true,
+	StylePlastic:     true,
+	StyleForTheBadge: true,
+}
+
+func NewBadge(value string) *Badge {
+	return &Badge{
+		Label:      DefaultLabel,
+		Value:      value,
+		Color:      DefaultColor,
+		LabelColor: DefaultLabelColor,
+		Style:      DefaultStyle,
+	}
+}
+
+// Parse applies the badge options provided by the given query. Invalid
+// values are ignored and the current value is kept instead.
+func (b *Badge) Parse(query url.Values) *Badge {
+	if label, ok := query["label"]; ok {
+		b.Label = cleanText(strings.TrimSpace(label[0]))
+	}
+	if prefix := query.Get("prefix"); prefix != "" {
+		b.Prefix = cleanText(prefix)
+	}
+	if suffix := query.Get("suffix"); suffix != "" {
+		b.Suffix = cleanText(suffix)
+	}
+	if color, ok := ParseColor(query.Get("color")); ok {
+		b.Color = color
+	}
+	if color, ok := ParseColor(query.Get("labelColor")); ok {
+		b.LabelColor = color
+	}
+	if style := strings.ToLower(query.Get("style")); styles[style] {
+		b.Style = style
+	}
+	if theme := query.Get("theme"); themeName.MatchString(theme) {
+		b.Theme = theme
+	}
+	return b
+}
+
+// Text returns the unescaped text displayed on the right side of the badge
+func (b *Badge) Text() string {
+	return b.Prefix + b.Value + b.Suffix
+}
+
+// SVG renders the badge using the built-in renderer
+func (b *Badge) SVG() string {
+	return render(b.Data())
+}
+
+func cleanText(in string) string {
+	if r := []rune(in); len(r) > maxTextLength {
+		in = string(r[:maxTextLength])
+	}
+	return in
+}
+
+func escape(in string) string {
+	return html.EscapeString(in)
+}
@@ -0,0 +1,24 @@
+package badge
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	query := url.Values{
+		"label":  {" views "},
+		"color":  {"invalid"},
+		"style":  {"PLASTIC"},
+		"theme":  {"../secret"},
+		"suffix": {strings.Repeat("x", 100)},
+	}
+	b := NewBadge("42").Parse(query)
+	if b.Label != "views" || b.Color != DefaultColor || b.Style != StylePlastic || b.Theme != "" {
+		t.Errorf("parsed %+v", b)
+	}
+	if len(b.Suffix) != maxTextLength {
+		t.Errorf("kept a suffix of %d characters, expected %d", len(b.Suffix), maxTextLength)
+	}
+}
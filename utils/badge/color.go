@@ -0,0 +1,41 @@
+package badge
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Named colors as known from shields.io
+var colors = map[string]string{
+	"brightgreen":   "#4c1",
+	"green":         "#97ca00",
+	"yellowgreen":   "#a4a61d",
+	"yellow":        "#dfb317",
+	"orange":        "#fe7d37",
+	"red":           "#e05d44",
+	"blue":          "#007ec6",
+	"lightgrey":     "#9f9f9f",
+	"lightgray":     "#9f9f9f",
+	"grey":          "#555",
+	"gray":          "#555",
+	"success":       "#4c1",
+	"important":     "#fe7d37",
+	"critical":      "#e05d44",
+	"informational": "#007ec6",
+	"inactive":      "#9f9f9f",
+}
+
+var hexColor = regexp.MustCompile("^#?([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$")
+
+// ParseColor resolves a named or hex color ("4c1", "#4c1", "44cc11"). The
+// second return value is false if the given value isn't a valid color.
+func ParseColor(in string) (string, bool) {
+	in = strings.TrimSpace(in)
+	if color, ok := colors[strings.ToLower(in)]; ok {
+		return color, true
+	}
+	if m := hexColor.FindStringSubmatch(in); m != nil {
+		return "#" + strings.ToLower(m[1]), true
+	}
+	return "", false
+}
@@ -0,0 +1,58 @@
+package badge
+
+import "math"
+
+// Number of font units per em of the Verdana font
+const unitsPerEm = 2048.0
+
+// Bold glyphs of Verdana are on average about 12% wider than regular ones
+const boldFactor = 1.12
+
+// Advance widths of the Verdana glyphs in font units, covering the printable
+// ASCII range from 0x20 (space) to 0x7e (tilde).
+var verdanaAdvances = [...]float64{
+	720, 807, 942, 1675, 1302, 2213, 1491, 548, 913, 913, 1302, 1675, 745, 874, 745, 913, // ' ' - '/'
+	1302, 1302, 1302, 1302, 1302, 1302, 1302, 1302, 1302, 1302, // '0' - '9'
+	913, 913, 1675, 1675, 1675, 1116, 2048, // ':' - '@'
+	1401, 1405, 1430, 1577, 1294, 1178, 1589, 1540, 862, 931, 1415, 1138, 1726, // 'A' - 'M'
+	1532, 1612, 1236, 1612, 1423, 1401, 1263, 1498, 1401, 2025, 1403, 1260, 1403, // 'N' - 'Z'
+	913, 913, 913, 1675, 1302, 1302, // '[' - '`'
+	1229, 1276, 1067, 1276, 1220, 720, 1276, 1296, 560, 700, 1205, 560, 1995, // 'a' - 'm'
+	1296, 1243, 1276, 1276, 874, 1067, 807, 1296, 1205, 1675, 1205, 1205, 1071, // 'n' - 'z'
+	1302, 913, 1302, 1675, // '{' - '~'
+}
+
+// Advance width used for glyphs outside of the known range; wide enough
+// to keep most latin, greek and cyrillic letters from being clipped.
+const fallbackAdvance = 1400
+
+// TextWidth returns the width in pixels the given text occupies if rendered
+// in Verdana with the given font size.
+func TextWidth(text string, fontSize float64, bold bool) float64 {
+	units := 0.0
+	for _, r := range text {
+		units += glyphAdvance(r)
+	}
+	width := units * fontSize / unitsPerEm
+	if bold {
+		width *= boldFactor
+	}
+	return width
+}
+
+func glyphAdvance(r rune) float64 {
+	if r >= 0x20 && int(r-0x20) < len(verdanaAdvances) {
+		return verdanaAdvances[r-0x20]
+	}
+	return fallbackAdvance
+}
+
+// roundWidth rounds the given width up to the next tenth of a pixel
+func roundWidth(width float64) float64 {
+	return math.Ceil(width*10) / 10
+}
+
+// round rounds the given value to two decimals to keep the svg output tidy
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
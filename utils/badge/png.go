@@ -0,0 +1,214 @@
+package badge
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"strconv"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// Maximum factor a png badge can be scaled up for high density displays
+const MaxScale = 4
+
+var (
+	fontOnce    sync.Once
+	fontErr     error
+	regularFont *opentype.Font
+	boldFont    *opentype.Font
+)
+
+// loadFonts parses the embedded Go fonts, which are used to rasterize the
+// badge text without depending on any fonts installed on the host.
+func loadFonts() error {
+	fontOnce.Do(func() {
+		if regularFont, fontErr = opentype.Parse(goregular.TTF); fontErr != nil {
+			return
+		}
+		boldFont, fontErr = opentype.Parse(gobold.TTF)
+	})
+	return fontErr
+}
+
+// PNG rasterizes the badge. The scale multiplies all dimensions and is
+// clamped between 1 and MaxScale.
+func (b *Badge) PNG(scale int) ([]byte, error) {
+	if err := loadFonts(); err != nil {
+		return nil, err
+	}
+	if scale < 1 {
+		scale = 1
+	} else if scale > MaxScale {
+		scale = MaxScale
+	}
+
+	g := newGeometry(b)
+	sf := float64(scale)
+	width := int(math.Ceil(g.Width() * sf))
+	height := g.Height * scale
+	labelWidth := int(math.Round(g.LabelWidth * sf))
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, image.Rect(0, 0, labelWidth, height), image.NewUniform(parseHex(b.LabelColor)), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(labelWidth, 0, width, height), image.NewUniform(parseHex(b.Color)), image.Point{}, draw.Src)
+	if len(g.Gradient) > 0 {
+		applyGradient(img, g.Gradient)
+	}
+
+	f := regularFont
+	if g.Bold {
+		f = boldFont
+	}
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{
+		Size:    g.FontSize * sf,
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer face.Close()
+
+	if g.LabelWidth > 0 {
+		drawText(img, face, g, g.LabelWidth/2*sf, sf, g.Label)
+	}
+	drawText(img, face, g, (g.LabelWidth+g.ValueWidth/2)*sf, sf, g.Value)
+
+	if g.Radius > 0 {
+		roundCorners(img, float64(g.Radius)*sf)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// drawText draws the given text horizontally centered at x, including the
+// text shadow used by some styles.
+func drawText(img *image.NRGBA, face font.Face, g *geometry, x float64, sf float64, text string) {
+	spacing := fixed.Int26_6(g.LetterSpacing * sf * 64)
+	runes := []rune(text)
+
+	width := fixed.Int26_6(0)
+	for _, r := range runes {
+		advance, _ := face.GlyphAdvance(r)
+		width += advance + spacing
+	}
+
+	start := fixed.Int26_6(x*64) - width/2
+	baseline := fixed.Int26_6(float64(g.TextY) * sf * 64)
+
+	if g.Shadow {
+		writeRunes(img, face, color.NRGBA{R: 1, G: 1, B: 1, A: 77}, start, baseline+fixed.Int26_6(sf*64), spacing, runes)
+	}
+	writeRunes(img, face, color.White, start, baseline, spacing, runes)
+}
+
+func writeRunes(img *image.NRGBA, face font.Face, c color.Color, x fixed.Int26_6, y fixed.Int26_6, spacing fixed.Int26_6, runes []rune) {
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(c),
+		Face: face,
+		Dot:  fixed.Point26_6{X: x, Y: y},
+	}
+	for _, r := range runes {
+		d.DrawString(string(r))
+		d.Dot.X += spacing
+	}
+}
+
+// applyGradient blends the vertical gradient defined by the given stops over
+// the whole image.
+func applyGradient(img *image.NRGBA, stops []Stop) {
+	bounds := img.Bounds()
+	height := float64(bounds.Dy())
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		c, alpha := gradientAt(stops, (float64(y-bounds.Min.Y)+.5)/height)
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			i := img.PixOffset(x, y)
+			img.Pix[i] = blend(img.Pix[i], c.R, alpha)
+			img.Pix[i+1] = blend(img.Pix[i+1], c.G, alpha)
+			img.Pix[i+2] = blend(img.Pix[i+2], c.B, alpha)
+		}
+	}
+}
+
+// gradientAt interpolates the color and opacity of the given stops at the
+// relative position t.
+func gradientAt(stops []Stop, t float64) (color.NRGBA, float64) {
+	if t <= stops[0].Offset {
+		return parseHex(stops[0].Color), stops[0].Opacity
+	}
+	for i := 1; i < len(stops); i++ {
+		if t <= stops[i].Offset {
+			from, to := stops[i-1], stops[i]
+			p := (t - from.Offset) / (to.Offset - from.Offset)
+			fc, tc := parseHex(from.Color), parseHex(to.Color)
+			return color.NRGBA{
+				R: mix(fc.R, tc.R, p),
+				G: mix(fc.G, tc.G, p),
+				B: mix(fc.B, tc.B, p),
+				A: 0xff,
+			}, from.Opacity + (to.Opacity-from.Opacity)*p
+		}
+	}
+	last := stops[len(stops)-1]
+	return parseHex(last.Color), last.Opacity
+}
+
+// roundCorners makes the pixels outside of the rounded corners with the
+// given radius transparent, using the covered area for anti-aliasing.
+func roundCorners(img *image.NRGBA, radius float64) {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	r := int(math.Ceil(radius))
+	for y := 0; y < r && y < h; y++ {
+		for x := 0; x < r && x < w; x++ {
+			dx := radius - (float64(x) + .5)
+			dy := radius - (float64(y) + .5)
+			coverage := math.Max(radius-math.Sqrt(dx*dx+dy*dy)+.5, 0)
+			if coverage >= 1 {
+				continue
+			}
+			for _, p := range []image.Point{{X: x, Y: y}, {X: w - 1 - x, Y: y}, {X: x, Y: h - 1 - y}, {X: w - 1 - x, Y: h - 1 - y}} {
+				i := img.PixOffset(bounds.Min.X+p.X, bounds.Min.Y+p.Y)
+				img.Pix[i+3] = uint8(float64(img.Pix[i+3]) * coverage)
+			}
+		}
+	}
+}
+
+// parseHex converts a color as returned by ParseColor into a color.NRGBA
+func parseHex(in string) color.NRGBA {
+	hex := in
+	if len(hex) > 0 && hex[0] == '#' {
+		hex = hex[1:]
+	}
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || len(hex) != 6 {
+		return color.NRGBA{A: 0xff}
+	}
+	return color.NRGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}
+}
+
+func blend(dst uint8, src uint8, alpha float64) uint8 {
+	return uint8(math.Round(float64(dst)*(1-alpha) + float64(src)*alpha))
+}
+
+func mix(from uint8, to uint8, p float64) uint8 {
+	return uint8(math.Round(float64(from) + (float64(to)-float64(from))*p))
+}
@@ -0,0 +1,35 @@
+package badge
+
+import (
+	"bytes"
+	"image/png"
+	"math"
+	"testing"
+)
+
+func TestPNG(t *testing.T) {
+	for _, scale := range []int{0, 1, 2, MaxScale + 1} {
+		b := NewBadge("42")
+		b.Style = StyleFlat
+		content, err := b.PNG(scale)
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := png.Decode(bytes.NewReader(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := scale
+		if expected < 1 {
+			expected = 1
+		} else if expected > MaxScale {
+			expected = MaxScale
+		}
+		d := b.Data()
+		width := int(math.Ceil(d.Width * float64(expected)))
+		if size := img.Bounds().Size(); size.X != width || size.Y != d.Height*expected {
+			t.Errorf("rendered %dx%d pixels at scale %d, expected %dx%d", size.X, size.Y, scale, width, d.Height*expected)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package badge
+
+import (
+	"fmt"
+	"strings"
+)
+
+const fontFamily = "DejaVu Sans,Verdana,Geneva,sans-serif"
+
+var (
+	flatGradient = []Stop{
+		{Offset: 0, Color: "#bbb", Opacity: .1},
+		{Offset: 1, Color: "#000", Opacity: .1},
+	}
+	plasticGradient = []Stop{
+		{Offset: 0, Color: "#fff", Opacity: .7},
+		{Offset: .1, Color: "#aaa", Opacity: .1},
+		{Offset: .9, Color: "#000", Opacity: .3},
+		{Offset: 1, Color: "#000", Opacity: .5},
+	}
+)
+
+type geometry struct {
+	Height        int
+	FontSize      float64
+	Bold          bool
+	LetterSpacing float64
+	Padding       float64
+	TextY         int
+	Radius        int
+	LabelWidth    float64
+	ValueWidth    float64
+	LabelText     float64
+	ValueText     float64
+	Label         string
+	Value         string
+	Gradient      []Stop
+	Shadow        bool
+}
+
+func (g *geometry) Width() float64 {
+	return round(g.LabelWidth + g.ValueWidth)
+}
+
+// measure returns the width of the given text including the letter spacing
+func (g *geometry) measure(text string) float64 {
+	n := float64(len([]rune(text)))
+	return roundWidth(TextWidth(text, g.FontSize, g.Bold) + n*g.LetterSpacing)
+}
+
+func newGeometry(b *Badge) *geometry {
+	g := &geometry{
+		Height:   20,
+		FontSize: 11,
+		Padding:  5,
+		TextY:    14,
+		Label:    b.Label,
+		Value:    b.Text(),
+	}
+
+	switch b.Style {
+	case StyleFlat:
+		g.Radius = 3
+		g.Shadow = true
+		g.Gradient = flatGradient
+	case StylePlastic:
+		g.Height = 18
+		g.TextY = 13
+		g.Radius = 4
+		g.Shadow = true
+		g.Gradient = plasticGradient
+	case StyleForTheBadge:
+		g.Height = 28
+		g.FontSize = 10
+		g.Bold = true
+		g.LetterSpacing = 1
+		g.Padding = 9
+		g.TextY = 18
+		g.Label = strings.ToUpper(g.Label)
+		g.Value = strings.ToUpper(g.Value)
+	}
+
+	if g.Label != "" {
+		g.LabelText = g.measure(g.Label)
+		g.LabelWidth = round(g.LabelText + 2*g.Padding)
+	}
+	g.ValueText = g.measure(g.Value)
+	g.ValueWidth = round(g.ValueText + 2*g.Padding)
+
+	return g
+}
+
+// render is the built-in renderer, used if no template is available for the
+// requested theme
+func render(d *Data) string {
+	svg := &strings.Builder{}
+	svg.WriteString(`<?xml version="1.0"?>`)
+	svg.WriteString(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%g" height="%d" role="img" aria-label="%s">`,
+		d.Width, d.Height, d.Title))
+
+	if len(d.Gradient) > 0 {
+		svg.WriteString(`<linearGradient id="s" x2="0" y2="100%">`)
+		for _, st := range d.Gradient {
+			svg.WriteString(fmt.Sprintf(`<stop offset="%g" stop-color="%s" stop-opacity="%g"/>`, st.Offset, st.Color, st.Opacity))
+		}
+		svg.WriteString(`</linearGradient>`)
+	}
+	svg.WriteString(fmt.Sprintf(`<clipPath id="r"><rect width="%g" height="%d" rx="%d" fill="#fff"/></clipPath>`,
+		d.Width, d.Height, d.Radius))
+
+	svg.WriteString(`<g clip-path="url(#r)">`)
+	if d.LabelWidth > 0 {
+		svg.WriteString(fmt.Sprintf(`<rect width="%g" height="%d" fill="%s"/>`, d.LabelWidth, d.Height, d.LabelColor))
+	}
+	svg.WriteString(fmt.Sprintf(`<rect x="%g" width="%g" height="%d" fill="%s"/>`, d.LabelWidth, d.ValueWidth, d.Height, d.Color))
+	if len(d.Gradient) > 0 {
+		svg.WriteString(fmt.Sprintf(`<rect width="%g" height="%d" fill="url(#s)"/>`, d.Width, d.Height))
+	}
+	svg.WriteString(`</g>`)
+
+	svg.WriteString(fmt.Sprintf(`<g fill="#fff" text-anchor="middle" font-family="%s" font-size="%g" font-weight="%s" letter-spacing="%g">`,
+		d.FontFamily, d.FontSize, d.FontWeight, d.LetterSpacing))
+	if d.LabelWidth > 0 {
+		writeText(svg, d, d.LabelX, d.LabelTextWidth, d.Label)
+	}
+	writeText(svg, d, d.ValueX, d.ValueTextWidth, d.Value)
+	svg.WriteString(`</g>`)
+
+	svg.WriteString(`</svg>`)
+
+	return svg.String()
+}
+
+// writeText writes the given, already escaped text centered at x. The
+// textLength attribute pins the text to its measured width, so viewers
+// falling back to another font won't overflow the badge.
+func writeText(svg *strings.Builder, d *Data, x float64, length float64, text string) {
+	if d.Shadow {
+		svg.WriteString(fmt.Sprintf(`<text x="%g" y="%d" fill="#010101" fill-opacity=".3" textLength="%g">%s</text>`, x, d.ShadowY, length, text))
+	}
+	svg.WriteString(fmt.Sprintf(`<text x="%g" y="%d" textLength="%g">%s</text>`, x, d.TextY, length, text))
+}
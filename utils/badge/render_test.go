@@ -0,0 +1,40 @@
+package badge
+
+import (
+	"testing"
+)
+
+func TestWidth(t *testing.T) {
+	tests := []struct {
+		label      string
+		value      string
+		style      string
+		labelWidth float64
+		valueWidth float64
+	}{
+		{"hits", "42", StyleFlatSquare, 30.1, 24},
+		{"hits", "1.2k", StyleFlat, 30.1, 34.5},
+		{"hits", "42", StyleForTheBadge, 49.8, 34.3},
+		{"", "42", StyleFlatSquare, 0, 24},
+	}
+	for _, test := range tests {
+		b := NewBadge(test.value)
+		b.Label = test.label
+		b.Style = test.style
+		d := b.Data()
+		if d.LabelWidth != test.labelWidth || d.ValueWidth != test.valueWidth {
+			t.Errorf("%s badge %q: %q is %g+%g wide, expected %g+%g", test.style, test.label, test.value, d.LabelWidth, d.ValueWidth, test.labelWidth, test.valueWidth)
+		}
+		if d.Width != round(d.LabelWidth+d.ValueWidth) {
+			t.Errorf("%s badge is %g wide, expected the sum of its parts", test.style, d.Width)
+		}
+	}
+
+	// Wider texts result in wider badges, regardless of their length
+	if TextWidth("WWW", 11, false) <= TextWidth("iii", 11, false) {
+		t.Error("measured WWW narrower than iii")
+	}
+	if TextWidth("hits", 11, true) <= TextWidth("hits", 11, false) {
+		t.Error("measured bold text narrower than regular text")
+	}
+}
@@ -0,0 +1,54 @@
+package badge
+
+type Badge struct {
+	Label      string
+	Value      string
+	Prefix     string
+	Suffix     string
+	Color      string
+	LabelColor string
+	Style      string
+	Theme      string
+}
+
+// Data is passed to badge templates. All texts are already escaped and all
+// dimensions are given in pixels.
+type Data struct {
+	Title      string
+	Label      string
+	Value      string
+	Color      string
+	LabelColor string
+	Style      string
+
+	FontFamily string
+	FontSize   float64
+	FontWeight string
+	// Letter spacing in pixels
+	LetterSpacing float64
+
+	Width      float64
+	Height     int
+	Radius     int
+	LabelWidth float64
+	ValueWidth float64
+	// Horizontal center of the label and the value
+	LabelX float64
+	ValueX float64
+	// Measured width of the label and value text
+	LabelTextWidth float64
+	ValueTextWidth float64
+	// Baseline of the text and its shadow
+	TextY   int
+	ShadowY int
+
+	Shadow   bool
+	Gradient []Stop
+}
+
+// Stop is a single color stop of the vertical gradient laid over the badge
+type Stop struct {
+	Offset  float64
+	Color   string
+	Opacity float64
+}
@@ -0,0 +1,71 @@
+package badge
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+)
+
+// Data returns the data passed to the badge templates
+func (b *Badge) Data() *Data {
+	g := newGeometry(b)
+
+	d := &Data{
+		Title:      escape(strings.TrimSpace(b.Label + ": " + b.Text())),
+		Label:      escape(g.Label),
+		Value:      escape(g.Value),
+		Color:      b.Color,
+		LabelColor: b.LabelColor,
+		Style:      b.Style,
+
+		FontFamily:    fontFamily,
+		FontSize:      g.FontSize,
+		FontWeight:    "normal",
+		LetterSpacing: g.LetterSpacing,
+
+		Width:          g.Width(),
+		Height:         g.Height,
+		Radius:         g.Radius,
+		LabelWidth:     g.LabelWidth,
+		ValueWidth:     g.ValueWidth,
+		LabelX:         round(g.LabelWidth / 2),
+		ValueX:         round(g.LabelWidth + g.ValueWidth/2),
+		LabelTextWidth: g.LabelText,
+		ValueTextWidth: g.ValueText,
+		TextY:          g.TextY,
+		ShadowY:        g.TextY + 1,
+
+		Shadow:   g.Shadow,
+		Gradient: g.Gradient,
+	}
+	if g.Bold {
+		d.FontWeight = "bold"
+	}
+
+	return d
+}
+
+// Render executes the template named after the badge theme, or the badge
+// style if no theme was requested. Templates are looked up by their file
+// name (e.g. "flat.tmpl"). The built-in renderer is used if no matching
+// template exists.
+func (b *Badge) Render(tpl *template.Template) (string, error) {
+	theme := b.Theme
+	if theme == "" {
+		theme = b.Style
+	}
+
+	var t *template.Template
+	if tpl != nil {
+		t = tpl.Lookup(theme + ".tmpl")
+	}
+	if t == nil {
+		return b.SVG(), nil
+	}
+
+	buf := &bytes.Buffer{}
+	if err := t.Execute(buf, b.Data()); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
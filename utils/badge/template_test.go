@@ -0,0 +1,76 @@
+package badge
+
+import (
+	"encoding/xml"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+// TestRender renders every style with the shipped templates and the built-in
+// renderer. Both have to produce valid svg documents of the same size.
+func TestRender(t *testing.T) {
+	templates := template.Must(template.ParseGlob(path.Join("..", "..", "htdocs", "template", "badges", "*.tmpl")))
+
+	for style := range styles {
+		b := NewBadge("1.2k")
+		b.Label = "<hits>"
+		b.Style = style
+
+		rendered, err := b.Render(templates)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, svg := range []string{rendered, b.SVG()} {
+			width, height := svgSize(t, svg)
+			if width != b.Data().Width || height != b.Data().Height {
+				t.Errorf("rendered %s badge as %gx%d, expected %gx%d", style, width, height, b.Data().Width, b.Data().Height)
+			}
+			if !strings.Contains(svg, "&lt;hits&gt;") {
+				t.Errorf("%s badge doesn't contain the escaped label", style)
+			}
+		}
+
+		// The plastic style has a glossy gradient of its own
+		if glossy := strings.Contains(rendered, `stop-opacity=".7"`); glossy != (style == StylePlastic) {
+			t.Errorf("rendered %s badge with the plastic gradient: %t", style, glossy)
+		}
+	}
+
+	// Unknown themes fall back to the built-in renderer
+	b := NewBadge("42")
+	b.Theme = "unknown"
+	if rendered, err := b.Render(templates); err != nil || rendered != b.SVG() {
+		t.Errorf("rendered an unknown theme with a template: %v", err)
+	}
+}
+
+// svgSize decodes the given svg document and returns the size of its root
+func svgSize(t *testing.T, svg string) (float64, int) {
+	t.Helper()
+	decoder := xml.NewDecoder(strings.NewReader(svg))
+	var width float64
+	var height int
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return width, height
+		}
+		if err != nil {
+			t.Fatalf("invalid svg: %v", err)
+		}
+		if element, ok := token.(xml.StartElement); ok && element.Name.Local == "svg" {
+			for _, attr := range element.Attr {
+				switch attr.Name.Local {
+				case "width":
+					width, _ = strconv.ParseFloat(attr.Value, 64)
+				case "height":
+					height, _ = strconv.Atoi(attr.Value)
+				}
+			}
+		}
+	}
+}
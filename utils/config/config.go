@@ -56,6 +56,42 @@ func DefaultConfig() *Config {
 		PingPeriod:       12 * time.Second,
 		CloseGracePeriod: 6 * time.Second,
 
+		VisitorIdentifier: "ip-ua",
+		BotFilter:         true,
+		BotPatternsFile:   path.Join(dir, "conf", "bots.txt"),
+		BotEmptyUserAgent: true,
+
+		ImageProxyUserAgents: "github-camo",
+		ImageProxyMode:       "dedupe",
+		ImageProxyWeight:     1,
+
+		JunkMinHits: 2,
+		JunkAge:     24 * time.Hour,
+		IdleTTL:     20 * time.Minute,
+
+		FlushInterval:    time.Minute,
+		FlushConcurrency: 4,
+		Storage:          "json",
+		StorageFile:      path.Join(dir, "data", "gohits.db"),
+
+		ReferrerLimit:      50,
+		UserAgentRulesFile: path.Join(dir, "conf", "useragents.txt"),
+
+		Metrics: false,
+
+		RegistrationMaxPages: 100,
+		RegistryFile:         path.Join(dir, "conf", "registry.json"),
+		AliasesFile:          path.Join(dir, "conf", "aliases.json"),
+
+		ShieldsCount:         true,
+		ShieldsCacheLifetime: 5 * time.Minute,
+
+		NumberFormat:    "short",
+		NumberPrecision: 1,
+		NumberLocale:    "en",
+
+		StatsCacheControl: "no-cache",
+
 		RootDir:        dir,
 		GuiDir:         "gui",
 		File:           path.Join(dir, "conf", "settings.config"),
@@ -93,6 +129,42 @@ func (c *Config) AddFlags(fs *flag.FlagSet) {
 	fs.StringVar(&c.GuiDir, "gui", c.GuiDir, "Web gui directory")
 
 	fs.DurationVar(&c.SessionLifetime, "session-lifetime", c.SessionLifetime, "Session lifetime of an counted visitor")
+	fs.StringVar(&c.VisitorIdentifier, "visitor-identifier", c.VisitorIdentifier, "Strategy used to identify visitors (ip, ip-ua, prefix or cookie)")
+	fs.BoolVar(&c.PrivacyMode, "privacy-mode", c.PrivacyMode, "Hash visitor identities using a daily rotating secret which is never persisted")
+	fs.BoolVar(&c.BotFilter, "bot-filter", c.BotFilter, "Count bots separately instead of as regular hits")
+	fs.StringVar(&c.BotPatternsFile, "bot-patterns", c.BotPatternsFile, "File containing additional bot patterns (one regular expression per line)")
+	fs.BoolVar(&c.BotEmptyUserAgent, "bot-empty-user-agent", c.BotEmptyUserAgent, "Count requests without a user agent as bots if bot-filter is enabled")
+	fs.StringVar(&c.ImageProxyUserAgents, "image-proxy-user-agents", c.ImageProxyUserAgents, "Comma separated list of user agents of trusted image proxies")
+	fs.StringVar(&c.ImageProxyCIDRs, "image-proxy-cidrs", c.ImageProxyCIDRs, "Comma separated list of networks image proxies are trusted from; none if empty")
+	fs.StringVar(&c.ImageProxyHeader, "image-proxy-header", c.ImageProxyHeader, "Header carrying the original client address of image proxy requests")
+	fs.StringVar(&c.ImageProxyMode, "image-proxy-mode", c.ImageProxyMode, "How image proxy requests are counted (dedupe, count or weight)")
+	fs.Float64Var(&c.ImageProxyWeight, "image-proxy-weight", c.ImageProxyWeight, "Hits counted per image proxy request in weight mode")
+	fs.Int64Var(&c.JunkMinHits, "junk-min-hits", c.JunkMinHits, "Sections with less hits are discarded as junk once they are older than junk-age")
+	fs.DurationVar(&c.JunkAge, "junk-age", c.JunkAge, "Age after which sections with less than junk-min-hits hits are discarded")
+	fs.BoolVar(&c.JunkDeleteFiles, "junk-delete-files", c.JunkDeleteFiles, "Delete the files of junk sections instead of only unloading them")
+	fs.DurationVar(&c.IdleTTL, "idle-ttl", c.IdleTTL, "Unload sections which haven't been requested within this duration; 0 keeps them loaded")
+	fs.IntVar(&c.MaxSections, "max-sections", c.MaxSections, "Maximum number of loaded sections, least recently used sections are unloaded first; 0 means unlimited")
+	fs.DurationVar(&c.FlushInterval, "flush-interval", c.FlushInterval, "Interval in which changed sections are saved")
+	fs.IntVar(&c.FlushConcurrency, "flush-concurrency", c.FlushConcurrency, "Maximum number of sections which are saved at the same time")
+	fs.StringVar(&c.Storage, "storage", c.Storage, "Storage backend of the sections (json or bolt)")
+	fs.StringVar(&c.StorageFile, "storage-file", c.StorageFile, "Database file used by the bolt storage backend")
+	fs.IntVar(&c.ReferrerLimit, "referrer-limit", c.ReferrerLimit, "Maximum number of referrers tracked per section; 0 disables referrer tracking")
+	fs.BoolVar(&c.ReferrerPaths, "referrer-paths", c.ReferrerPaths, "Track the path of referrers in addition to their host")
+	fs.StringVar(&c.UserAgentRulesFile, "user-agent-rules", c.UserAgentRulesFile, "Rules used to break down hits by browser, operating system and device; disabled if empty")
+	fs.StringVar(&c.GeoIPDatabase, "geoip-database", c.GeoIPDatabase, "MaxMind format database (.mmdb) used to break down hits by country")
+	fs.BoolVar(&c.Metrics, "metrics", c.Metrics, "Expose internal counters under /metrics, which isn't authenticated")
+	fs.BoolVar(&c.ShieldsCount, "shields-count", c.ShieldsCount, "Count requests to the shields.io endpoint as hits")
+	fs.DurationVar(&c.ShieldsCacheLifetime, "shields-cache-lifetime", c.ShieldsCacheLifetime, "Time shields.io is asked to cache the endpoint response")
+	fs.StringVar(&c.NumberFormat, "number-format", c.NumberFormat, "Default number format of the badges (short, full or grouped)")
+	fs.IntVar(&c.NumberPrecision, "number-precision", c.NumberPrecision, "Default number of decimals used by the short number format")
+	fs.StringVar(&c.NumberLocale, "number-locale", c.NumberLocale, "Default locale used to format numbers (en, de, es, it, nl, fr or ch)")
+	fs.StringVar(&c.StatsCacheControl, "stats-cache-control", c.StatsCacheControl, "Cache-Control header of the json, xml and csv stats")
+	fs.StringVar(&c.ViewSecret, "view-secret", c.ViewSecret, "Secret used to sign view tokens; view only badges are disabled if empty")
+	fs.BoolVar(&c.RegistrationMode, "registration-mode", c.RegistrationMode, "Only count sections which are registered or belong to a registered owner")
+	fs.IntVar(&c.RegistrationMaxPages, "registration-max-pages", c.RegistrationMaxPages, "Maximum number of pages per registered repository in the registration mode; 0 means unlimited")
+	fs.StringVar(&c.RegistryFile, "registry-file", c.RegistryFile, "File holding the registered sections and owners")
+	fs.StringVar(&c.AliasesFile, "aliases-file", c.AliasesFile, "File holding the section aliases")
+	fs.StringVar(&c.AdminToken, "admin-token", c.AdminToken, "Bearer token required by the admin api; the admin api is disabled if empty")
 	fs.DurationVar(&c.PongWait, "pong-wait", c.PongWait, "Websocket pong wait duration")
 	fs.DurationVar(&c.PingPeriod, "ping-period", c.PingPeriod, "Send pings to peer with this period. Must be less than pong-wait.")
 
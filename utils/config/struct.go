@@ -50,6 +50,89 @@ type Config struct {
 
 	SessionLifetime time.Duration `json:"SESSION_LIFETIME"`
 
+	// Strategy used to identify visitors (ip, ip-ua, prefix or cookie).
+	VisitorIdentifier string `json:"VISITOR_IDENTIFIER"`
+	// Strategies overriding the default per section (username/repository).
+	SectionVisitorIdentifiers map[string]string `json:"SECTION_VISITOR_IDENTIFIERS"`
+	// Hash visitor identities using a daily rotating secret which is never persisted.
+	PrivacyMode bool `json:"PRIVACY_MODE"`
+
+	// Count bots separately instead of as regular hits.
+	BotFilter bool `json:"BOT_FILTER"`
+	// File containing additional bot patterns, reloaded on change.
+	BotPatternsFile string `json:"BOT_PATTERNS_FILE"`
+	// Count requests without a user agent as bots.
+	BotEmptyUserAgent bool `json:"BOT_EMPTY_USER_AGENT"`
+
+	// Comma separated list of user agents of trusted image proxies (e.g. github-camo).
+	ImageProxyUserAgents string `json:"IMAGE_PROXY_USER_AGENTS"`
+	// Comma separated list of networks image proxies are trusted from; none if empty.
+	ImageProxyCIDRs string `json:"IMAGE_PROXY_CIDRS"`
+	// Header carrying the original client address (e.g. X-Forwarded-For).
+	ImageProxyHeader string `json:"IMAGE_PROXY_HEADER"`
+	// How image proxy requests without client address are counted (dedupe, count or weight).
+	ImageProxyMode string `json:"IMAGE_PROXY_MODE"`
+	// Hits counted per image proxy request in weight mode.
+	ImageProxyWeight float64 `json:"IMAGE_PROXY_WEIGHT"`
+
+	// Maximum number of referrers tracked per section; 0 disables referrer tracking.
+	ReferrerLimit int `json:"REFERRER_LIMIT"`
+	// Track the path of referrers in addition to their host.
+	ReferrerPaths bool `json:"REFERRER_PATHS"`
+	// Rules used to break down hits by browser, operating system and device; disabled if empty.
+	UserAgentRulesFile string `json:"USER_AGENT_RULES_FILE"`
+	// MaxMind format database used to break down hits by country.
+	GeoIPDatabase string `json:"GEOIP_DATABASE"`
+
+	// Sections with less hits are discarded as junk once they are older than JUNK_AGE.
+	JunkMinHits int64         `json:"JUNK_MIN_HITS"`
+	JunkAge     time.Duration `json:"JUNK_AGE"`
+	// Delete the files of junk sections instead of only unloading them.
+	JunkDeleteFiles bool `json:"JUNK_DELETE_FILES"`
+	// Sections which haven't been requested within this duration are unloaded; 0 keeps them loaded.
+	IdleTTL time.Duration `json:"IDLE_TTL"`
+	// Maximum number of loaded sections, least recently used sections are unloaded first; 0 means unlimited.
+	MaxSections int `json:"MAX_SECTIONS"`
+	// Changed sections are saved within this interval.
+	FlushInterval time.Duration `json:"FLUSH_INTERVAL"`
+	// Maximum number of sections which are saved at the same time.
+	FlushConcurrency int `json:"FLUSH_CONCURRENCY"`
+	// Storage backend of the sections (json or bolt).
+	Storage string `json:"STORAGE"`
+	// Database file used by the bolt storage backend.
+	StorageFile string `json:"STORAGE_FILE"`
+
+	// Expose internal counters under /metrics.
+	Metrics bool `json:"METRICS"`
+
+	// Count requests to the shields.io endpoint as hits.
+	ShieldsCount bool `json:"SHIELDS_COUNT"`
+	// Time shields.io is asked to cache the endpoint response.
+	ShieldsCacheLifetime time.Duration `json:"SHIELDS_CACHE_LIFETIME"`
+
+	// Default number format used by the badges (short, full or grouped).
+	NumberFormat    string `json:"NUMBER_FORMAT"`
+	NumberPrecision int    `json:"NUMBER_PRECISION"`
+	NumberLocale    string `json:"NUMBER_LOCALE"`
+
+	// Cache-Control header of the json, xml and csv stats.
+	StatsCacheControl string `json:"STATS_CACHE_CONTROL"`
+
+	// Secret used to sign view tokens. View only badges are disabled if empty.
+	ViewSecret string `json:"VIEW_SECRET"`
+
+	// Only count sections which are registered or belong to a registered owner.
+	RegistrationMode bool `json:"REGISTRATION_MODE"`
+	// Maximum number of pages per registered repository in the registration
+	// mode. 0 means unlimited.
+	RegistrationMaxPages int `json:"REGISTRATION_MAX_PAGES"`
+	// File holding the registered sections and owners.
+	RegistryFile string `json:"REGISTRY_FILE"`
+	// File holding the section aliases.
+	AliasesFile string `json:"ALIASES_FILE"`
+	// Bearer token required by the admin api. The admin api is disabled if empty.
+	AdminToken string `json:"ADMIN_TOKEN"`
+
 	// Maximum message size allowed from peer.
 	MaxMessageSize int64 `json:"MAX_MESSAGE_SIZE"`
 	// Time allowed to read the next pong message from the peer.
@@ -0,0 +1,159 @@
+package counter
+
+import (
+	"../filesystem"
+	"encoding/json"
+	bolt "go.etcd.io/bbolt"
+	"time"
+)
+
+var (
+	sectionsBucket = []byte("sections")
+	historyBucket  = []byte("history")
+	metaBucket     = []byte("meta")
+	indexKey       = []byte("index")
+)
+
+// BoltStore stores all sections and the index in a single bolt database. The
+// history of a section is kept in a bucket of its own, since it's by far the
+// largest part and rarely needed on its own.
+type BoltStore struct {
+	File string
+
+	db *bolt.DB
+}
+
+// NewBoltStore opens the given database file. Only one process can open the
+// database at a time.
+func NewBoltStore(file string) (*BoltStore, error) {
+	if _, err := filesystem.MakeDir(file); err != nil {
+		return nil, err
+	}
+	db, err := bolt.Open(file, 0644, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{sectionsBucket, historyBucket, metaBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &BoltStore{
+		File: file,
+		db:   db,
+	}, nil
+}
+
+func (b *BoltStore) Load(sectionKey string) (*Record, error) {
+	record := &Record{}
+	err := b.db.View(func(tx *bolt.Tx) error {
+		content := tx.Bucket(sectionsBucket).Get([]byte(sectionKey))
+		if content == nil {
+			return ErrNotStored
+		}
+		if err := json.Unmarshal(content, record); err != nil {
+			return err
+		}
+		if content := tx.Bucket(historyBucket).Get([]byte(sectionKey)); content != nil {
+			return json.Unmarshal(content, &record.History)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func (b *BoltStore) Save(sectionKey string, record *Record) error {
+	history, err := json.Marshal(record.History)
+	if err != nil {
+		return err
+	}
+	data := *record
+	data.History = nil
+	content, err := json.Marshal(&data)
+	if err != nil {
+		return err
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(sectionsBucket).Put([]byte(sectionKey), content); err != nil {
+			return err
+		}
+		return tx.Bucket(historyBucket).Put([]byte(sectionKey), history)
+	})
+}
+
+func (b *BoltStore) Delete(sectionKey string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(sectionsBucket).Delete([]byte(sectionKey)); err != nil {
+			return err
+		}
+		return tx.Bucket(historyBucket).Delete([]byte(sectionKey))
+	})
+}
+
+func (b *BoltStore) List() ([]string, error) {
+	var keys []string
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(sectionsBucket).ForEach(func(k []byte, v []byte) error {
+			keys = append(keys, string(k))
+			return nil
+		})
+	})
+	return keys, err
+}
+
+func (b *BoltStore) History(sectionKey string) (*History, error) {
+	var history *History
+	err := b.db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(sectionsBucket).Get([]byte(sectionKey)) == nil {
+			return ErrNotStored
+		}
+		content := tx.Bucket(historyBucket).Get([]byte(sectionKey))
+		if content == nil {
+			return nil
+		}
+		return json.Unmarshal(content, &history)
+	})
+	return history, err
+}
+
+func (b *BoltStore) LoadIndex() (map[string]map[string]bool, error) {
+	index := &indexRecord{}
+	err := b.db.View(func(tx *bolt.Tx) error {
+		content := tx.Bucket(metaBucket).Get(indexKey)
+		if content == nil {
+			return ErrNotStored
+		}
+		return json.Unmarshal(content, index)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return index.Children, nil
+}
+
+func (b *BoltStore) SaveIndex(children map[string]map[string]bool) error {
+	content, err := json.Marshal(&indexRecord{Children: children})
+	if err != nil {
+		return err
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(metaBucket).Put(indexKey, content)
+	})
+}
+
+func (b *BoltStore) Close() error {
+	return b.db.Close()
+}
@@ -0,0 +1,96 @@
+package counter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+func NewClients() *Clients {
+	return &Clients{
+		Browsers:         make(map[string]int64),
+		OperatingSystems: make(map[string]int64),
+		Devices:          make(map[string]int64),
+	}
+}
+
+// AddClient counts a hit of the given client for the loaded section of the
+// key of the given section, see AddEntry
+func (c *Counter) AddClient(section *Section, browser string, os string, device string) {
+	c.apply(section, func(section *Section) bool {
+		return section.AddClient(browser, os, device)
+	})
+}
+
+// AddClient counts a hit of the given browser, operating system and device
+// class. It returns false if the section has been closed.
+func (s *Section) AddClient(browser string, os string, device string) bool {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.closed {
+		return false
+	}
+	s.changed()
+	if s.Clients == nil {
+		s.Clients = NewClients()
+	}
+	s.Clients.Browsers[browser]++
+	s.Clients.OperatingSystems[os]++
+	s.Clients.Devices[device]++
+	return true
+}
+
+func (s *Section) GetClients() *ClientReport {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	clients := s.Clients
+	if clients == nil {
+		clients = NewClients()
+	}
+
+	return &ClientReport{
+		Username:         s.Username,
+		Repository:       s.Repository,
+		Browsers:         sortShares(clients.Browsers),
+		OperatingSystems: sortShares(clients.OperatingSystems),
+		Devices:          sortShares(clients.Devices),
+	}
+}
+
+// sortShares returns the given hits ordered by their number
+func sortShares(hits map[string]int64) []*Share {
+	shares := make([]*Share, 0, len(hits))
+	for name, n := range hits {
+		shares = append(shares, &Share{Name: name, Hits: n})
+	}
+	sort.Slice(shares, func(i, j int) bool {
+		if shares[i].Hits == shares[j].Hits {
+			return shares[i].Name < shares[j].Name
+		}
+		return shares[i].Hits > shares[j].Hits
+	})
+	return shares
+}
+
+func (r *ClientReport) String() string {
+	var lines []string
+	for _, group := range []struct {
+		field  string
+		shares []*Share
+	}{
+		{"browser", r.Browsers},
+		{"os", r.OperatingSystems},
+		{"device", r.Devices},
+	} {
+		for _, share := range group.shares {
+			lines = append(lines, strings.Join([]string{
+				r.Username,
+				r.Repository,
+				group.field,
+				share.Name,
+				fmt.Sprintf("%d", share.Hits),
+			}, ","))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,144 @@
+package counter
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	stressWorkers      = 8
+	stressIterations   = 500
+	stressRepositories = 20
+)
+
+func tempDir(t testing.TB) string {
+	dir, err := ioutil.TempDir("", "counter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func openStore(t testing.TB, backend string, dir string) Store {
+	if backend == StorageBolt {
+		store, err := NewBoltStore(path.Join(dir, "gohits.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return store
+	}
+	return NewFileStore(dir)
+}
+
+// TestConcurrentHits counts hits while sections are flushed, swept and
+// evicted at the same time. Every hit has to be persisted exactly once.
+func TestConcurrentHits(t *testing.T) {
+	for _, backend := range []string{StorageJSON, StorageBolt} {
+		t.Run(backend, func(t *testing.T) {
+			dir := tempDir(t)
+			defer os.RemoveAll(dir)
+
+			c := NewCounterWithStore(time.Hour, openStore(t, backend, dir))
+			c.Policy.MaxSections = shardCount
+			c.Policy.IdleTTL = time.Millisecond
+
+			stop := make(chan bool)
+			maintained := make(chan bool)
+			go func() {
+				defer close(maintained)
+				for {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+					c.Flush()
+					for _, sh := range c.shards {
+						c.sweep(sh, time.Now())
+					}
+				}
+			}()
+
+			var hits, bots, entries int64
+			wg := &sync.WaitGroup{}
+			for w := 0; w < stressWorkers; w++ {
+				wg.Add(1)
+				go func(w int) {
+					defer wg.Done()
+					for i := 0; i < stressIterations; i++ {
+						repository := fmt.Sprintf("repository-%d", i%stressRepositories)
+						section := c.GetPage("user", repository, "")
+						if c.AddEntry(section, NewEntry(fmt.Sprintf("%d-%d", w, i))) {
+							c.AddCountry(section, "DE")
+							atomic.AddInt64(&hits, 1)
+							atomic.AddInt64(&entries, 1)
+						}
+						if i%5 == 0 {
+							c.Increment(c.GetPage("user", repository, "page"))
+							atomic.AddInt64(&hits, 1)
+						}
+						if i%7 == 0 {
+							c.IncrementBot(c.GetPage("user", repository, ""))
+							atomic.AddInt64(&bots, 1)
+						}
+					}
+				}(w)
+			}
+			wg.Wait()
+			close(stop)
+			<-maintained
+			if err := c.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			c = NewCounterWithStore(time.Hour, openStore(t, backend, dir))
+			defer c.Close()
+			total, botTotal, countries := int64(0), int64(0), int64(0)
+			for i := 0; i < stressRepositories; i++ {
+				repository := fmt.Sprintf("repository-%d", i)
+				section := c.GetPage("user", repository, "")
+				total += section.GetMetric(MetricTotal)
+				botTotal += section.BotTotal
+				countries += section.Countries["DE"]
+				total += c.GetPage("user", repository, "page").GetMetric(MetricTotal)
+			}
+			if expected := int64(stressWorkers * stressIterations * 6 / 5); hits != expected {
+				t.Errorf("counted %d hits, expected %d", hits, expected)
+			}
+			if total != hits {
+				t.Errorf("persisted %d hits, counted %d", total, hits)
+			}
+			if botTotal != bots {
+				t.Errorf("persisted %d bot hits, counted %d", botTotal, bots)
+			}
+			if countries != entries {
+				t.Errorf("persisted %d countries, counted %d", countries, entries)
+			}
+			if n := len(c.Index.Get("user")); n != stressRepositories {
+				t.Errorf("indexed %d repositories, expected %d", n, stressRepositories)
+			}
+		})
+	}
+}
+
+func BenchmarkHit(b *testing.B) {
+	dir := tempDir(b)
+	defer os.RemoveAll(dir)
+	c := NewCounterWithStore(time.Hour, NewFileStore(dir))
+
+	var n int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			i := atomic.AddInt64(&n, 1)
+			section := c.GetPage("user", fmt.Sprintf("repository-%d", i%100), "")
+			c.AddEntry(section, NewEntry(fmt.Sprintf("%d", i)))
+		}
+	})
+}
@@ -0,0 +1,131 @@
+package counter
+
+import (
+	"../filesystem"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+)
+
+// FileStore stores every section in a json file of its own, named by the
+// hash of its key, and the index in index.json
+type FileStore struct {
+	Dir string
+}
+
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{
+		Dir: dir,
+	}
+}
+
+func (f *FileStore) filename(sectionKey string) string {
+	return path.Join(f.Dir, token(sectionKey)+".json")
+}
+
+func (f *FileStore) Load(sectionKey string) (*Record, error) {
+	record := &Record{}
+	if err := f.read(sectionKey, record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func (f *FileStore) read(sectionKey string, v interface{}) error {
+	content, err := ioutil.ReadFile(f.filename(sectionKey))
+	if os.IsNotExist(err) {
+		return ErrNotStored
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(content, v)
+}
+
+func (f *FileStore) Save(sectionKey string, record *Record) error {
+	content, err := json.MarshalIndent(record, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	filename := f.filename(sectionKey)
+	if _, err := filesystem.MakeDir(filename); err != nil {
+		return err
+	}
+	return filesystem.WriteFileAtomic(filename, content, 0644)
+}
+
+func (f *FileStore) Delete(sectionKey string) error {
+	if err := os.Remove(f.filename(sectionKey)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// List decodes all section files, since their names don't reveal the key
+func (f *FileStore) List() ([]string, error) {
+	files, err := filepath.Glob(path.Join(f.Dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		s := &Section{}
+		if err := json.Unmarshal(content, s); err != nil || s.Username == "" {
+			continue
+		}
+		keys = append(keys, s.key())
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (f *FileStore) History(sectionKey string) (*History, error) {
+	data := &struct {
+		History *History `json:"history"`
+	}{}
+	if err := f.read(sectionKey, data); err != nil {
+		return nil, err
+	}
+	return data.History, nil
+}
+
+func (f *FileStore) LoadIndex() (map[string]map[string]bool, error) {
+	content, err := ioutil.ReadFile(path.Join(f.Dir, "index.json"))
+	if os.IsNotExist(err) {
+		return nil, ErrNotStored
+	}
+	if err != nil {
+		return nil, err
+	}
+	index := &indexRecord{}
+	if err := json.Unmarshal(content, index); err != nil {
+		return nil, err
+	}
+	return index.Children, nil
+}
+
+func (f *FileStore) SaveIndex(children map[string]map[string]bool) error {
+	content, err := json.Marshal(&indexRecord{Children: children})
+	if err != nil {
+		return err
+	}
+
+	filename := path.Join(f.Dir, "index.json")
+	if _, err := filesystem.MakeDir(filename); err != nil {
+		return err
+	}
+	return filesystem.WriteFileAtomic(filename, content, 0644)
+}
+
+func (f *FileStore) Close() error {
+	return nil
+}
@@ -0,0 +1,67 @@
+package counter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// AddCountry counts a hit from the given country for the loaded section of
+// the key of the given section, see AddEntry
+func (c *Counter) AddCountry(section *Section, country string) {
+	c.apply(section, func(section *Section) bool {
+		return section.AddCountry(country)
+	})
+}
+
+// AddCountry counts a hit from the given country. It returns false if the
+// section has been closed.
+func (s *Section) AddCountry(country string) bool {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.closed {
+		return false
+	}
+	if country == "" {
+		return true
+	}
+	s.changed()
+	if s.Countries == nil {
+		s.Countries = make(map[string]int64)
+	}
+	s.Countries[country]++
+	return true
+}
+
+func (s *Section) GetCountries() *CountryReport {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	report := &CountryReport{
+		Username:   s.Username,
+		Repository: s.Repository,
+		Countries:  make([]*Country, 0, len(s.Countries)),
+	}
+	for code, hits := range s.Countries {
+		report.Countries = append(report.Countries, &Country{Code: code, Hits: hits})
+	}
+	sort.Slice(report.Countries, func(i, j int) bool {
+		if report.Countries[i].Hits == report.Countries[j].Hits {
+			return report.Countries[i].Code < report.Countries[j].Code
+		}
+		return report.Countries[i].Hits > report.Countries[j].Hits
+	})
+	return report
+}
+
+func (r *CountryReport) String() string {
+	lines := make([]string, len(r.Countries))
+	for i, country := range r.Countries {
+		lines[i] = strings.Join([]string{
+			r.Username,
+			r.Repository,
+			country.Code,
+			fmt.Sprintf("%d", country.Hits),
+		}, ",")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,170 @@
+package counter
+
+import (
+	"../log"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	GranularityHour = "hour"
+	GranularityDay  = "day"
+
+	// Hourly buckets older than this are discarded
+	HourlyRetention = 31 * 24 * time.Hour
+	// Daily buckets older than this are discarded
+	DailyRetention = 5 * 365 * 24 * time.Hour
+
+	// Maximum number of buckets a single report may contain
+	MaxReportBuckets = 2000
+)
+
+func NewHistory() *History {
+	return &History{
+		Hourly: make(map[int64]int64),
+		Daily:  make(map[int64]int64),
+	}
+}
+
+// Add counts n hits into the buckets matching the given time
+func (h *History) Add(t time.Time, n int64) {
+	if h.Hourly == nil || h.Daily == nil {
+		*h = *NewHistory()
+	}
+
+	hour := truncate(t, GranularityHour).Unix()
+	if _, ok := h.Hourly[hour]; !ok {
+		h.prune(t)
+	}
+	h.Hourly[hour] += n
+	h.Daily[truncate(t, GranularityDay).Unix()] += n
+}
+
+// Merge adds all buckets of the given history
+func (h *History) Merge(other *History) {
+	if h.Hourly == nil || h.Daily == nil {
+		*h = *NewHistory()
+	}
+	for k, n := range other.Hourly {
+		h.Hourly[k] += n
+	}
+	for k, n := range other.Daily {
+		h.Daily[k] += n
+	}
+}
+
+// Get returns the number of hits recorded in the bucket starting at t
+func (h *History) Get(t time.Time, granularity string) int64 {
+	if granularity == GranularityHour {
+		return h.Hourly[t.Unix()]
+	}
+	return h.Daily[t.Unix()]
+}
+
+// prune removes all buckets which exceeded their retention
+func (h *History) prune(now time.Time) {
+	hourly := now.Add(-HourlyRetention).Unix()
+	for k := range h.Hourly {
+		if k < hourly {
+			delete(h.Hourly, k)
+		}
+	}
+	daily := now.Add(-DailyRetention).Unix()
+	for k := range h.Daily {
+		if k < daily {
+			delete(h.Daily, k)
+		}
+	}
+}
+
+// Report builds a zero filled list of buckets between from and to. The range
+// is shortened to the most recent MaxReportBuckets buckets if necessary.
+func (h *History) Report(from time.Time, to time.Time, granularity string) []*Bucket {
+	if granularity != GranularityHour {
+		granularity = GranularityDay
+	}
+	step := granularityStep(granularity)
+
+	to = truncate(to, granularity)
+	from = truncate(from, granularity)
+	if earliest := to.Add(-step * (MaxReportBuckets - 1)); from.Before(earliest) {
+		from = earliest
+	}
+
+	var buckets []*Bucket
+	for t := from; !t.After(to); t = t.Add(step) {
+		buckets = append(buckets, &Bucket{
+			Time: t,
+			Hits: h.Get(t, granularity),
+		})
+	}
+	return buckets
+}
+
+// GetHistory reports the history of the given section. Sections which
+// aren't loaded are reported from the store without loading them.
+func (c *Counter) GetHistory(username string, repository string, page string, from time.Time, to time.Time, granularity string) *HistoryReport {
+	if section := c.GetSectionByKey(SectionKey(username, repository, page)); section != nil {
+		return section.GetHistory(from, to, granularity)
+	}
+
+	history, err := c.Store.History(SectionKey(username, repository, page))
+	if err != nil && err != ErrNotStored {
+		log.Error(err)
+	}
+	if history == nil {
+		history = NewHistory()
+	}
+	return newHistoryReport(username, repository, page, history, from, to, granularity)
+}
+
+func newHistoryReport(username string, repository string, page string, history *History, from time.Time, to time.Time, granularity string) *HistoryReport {
+	if granularity != GranularityHour {
+		granularity = GranularityDay
+	}
+	buckets := history.Report(from, to, granularity)
+
+	report := &HistoryReport{
+		Username:    username,
+		Repository:  repository,
+		Page:        page,
+		Granularity: granularity,
+		Buckets:     buckets,
+	}
+	if len(buckets) > 0 {
+		report.From = buckets[0].Time
+		report.To = buckets[len(buckets)-1].Time
+	}
+	return report
+}
+
+func (r *HistoryReport) String() string {
+	dateFormat := "2006-01-02 15:04:05"
+	lines := make([]string, len(r.Buckets))
+	for i, b := range r.Buckets {
+		lines[i] = strings.Join([]string{
+			r.Username,
+			r.Repository,
+			r.Page,
+			b.Time.Format(dateFormat),
+			fmt.Sprintf("%d", b.Hits),
+		}, ",")
+	}
+	return strings.Join(lines, "\n")
+}
+
+func granularityStep(granularity string) time.Duration {
+	if granularity == GranularityHour {
+		return time.Hour
+	}
+	return 24 * time.Hour
+}
+
+func truncate(t time.Time, granularity string) time.Time {
+	t = t.UTC()
+	if granularity == GranularityHour {
+		return t.Truncate(time.Hour)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
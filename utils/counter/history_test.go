@@ -0,0 +1,72 @@
+package counter
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestHistoryOfEvictedSection reports the history of a new section which has
+// been evicted before it has been saved
+func TestHistoryOfEvictedSection(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	c := NewCounterWithStore(time.Hour, NewFileStore(dir))
+	defer c.Close()
+	c.Policy.MaxSections = shardCount
+
+	repositories := sameShard(c, 2)
+	c.Increment(c.GetPage("user", repositories[0], ""))
+	c.GetPage("user", repositories[1], "")
+	sectionKey := SectionKey("user", repositories[0], "")
+	if _, ok := c.shard(sectionKey).evicted[sectionKey]; !ok {
+		t.Fatal("section hasn't been evicted")
+	}
+
+	now := time.Now()
+	report := c.GetHistory("user", repositories[0], "", now, now, GranularityHour)
+	if hits := reportedHits(report); hits != 1 {
+		t.Errorf("reported %d hits, expected 1", hits)
+	}
+}
+
+// sameShard returns n repositories of the user "user" whose sections belong
+// to the same shard
+func sameShard(c *Counter, n int) []string {
+	var repositories []string
+	sh := c.shard(SectionKey("user", "repository-0", ""))
+	for i := 0; len(repositories) < n; i++ {
+		repository := fmt.Sprintf("repository-%d", i)
+		if c.shard(SectionKey("user", repository, "")) == sh {
+			repositories = append(repositories, repository)
+		}
+	}
+	return repositories
+}
+
+func TestHistoryReportOfPage(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	c := NewCounterWithStore(time.Hour, NewFileStore(dir))
+	defer c.Close()
+	c.Increment(c.GetPage("user", "repository", "docs/install"))
+
+	now := time.Now()
+	day := now.UTC().Format("2006-01-02") + " 00:00:00"
+	tests := map[string]string{
+		"":             "user,repository,," + day + ",0",
+		"docs/install": "user,repository,docs/install," + day + ",1",
+	}
+	for page, expected := range tests {
+		report := c.GetHistory("user", "repository", page, now, now, GranularityDay)
+		if report.Page != page {
+			t.Errorf("reported page %q, expected %q", report.Page, page)
+		}
+		if csv := report.String(); csv != expected {
+			t.Errorf("reported %q, expected %q", csv, expected)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package counter
+
+import (
+	"hash/fnv"
+	"math"
+	"math/bits"
+)
+
+// Number of index bits of a HyperLogLog, it uses 2^precision registers
+const hyperLogLogPrecision = 10
+
+func NewHyperLogLog() *HyperLogLog {
+	return &HyperLogLog{
+		Registers: make([]uint8, 1<<hyperLogLogPrecision),
+	}
+}
+
+// Add adds the given value and reports whether the estimate changed
+func (h *HyperLogLog) Add(value string) bool {
+	h.init()
+	f := fnv.New64a()
+	f.Write([]byte(value))
+	x := mix(f.Sum64())
+
+	i := x >> (64 - hyperLogLogPrecision)
+	rank := uint8(bits.LeadingZeros64(x<<hyperLogLogPrecision|1<<(hyperLogLogPrecision-1)) + 1)
+	previous := h.Registers[i]
+	if rank <= previous {
+		return false
+	}
+	h.Registers[i] = rank
+	if h.counted {
+		h.sum += math.Ldexp(1, -int(rank)) - math.Ldexp(1, -int(previous))
+		if previous == 0 {
+			h.zeros--
+		}
+	}
+	return true
+}
+
+// Count returns the estimated number of distinct values. Small numbers are
+// counted by the number of empty registers, which is almost exact.
+func (h *HyperLogLog) Count() int64 {
+	h.init()
+	if !h.counted {
+		h.count()
+	}
+	m := float64(len(h.Registers))
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / h.sum
+	if estimate <= 2.5*m && h.zeros > 0 {
+		estimate = m * math.Log(m/float64(h.zeros))
+	}
+	return int64(estimate + 0.5)
+}
+
+// count sums up all registers, Add keeps the sums up to date afterwards
+func (h *HyperLogLog) count() {
+	h.sum = 0
+	h.zeros = 0
+	for _, r := range h.Registers {
+		h.sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			h.zeros++
+		}
+	}
+	h.counted = true
+}
+
+// Merge adds all values of the given HyperLogLog
+func (h *HyperLogLog) Merge(other *HyperLogLog) {
+	h.init()
+	if other == nil || len(other.Registers) != len(h.Registers) {
+		return
+	}
+	for i, r := range other.Registers {
+		if r > h.Registers[i] {
+			h.Registers[i] = r
+		}
+	}
+	h.counted = false
+}
+
+func (h *HyperLogLog) copy() *HyperLogLog {
+	c := &HyperLogLog{
+		Registers: make([]uint8, len(h.Registers)),
+		sum:       h.sum,
+		zeros:     h.zeros,
+		counted:   h.counted,
+	}
+	copy(c.Registers, h.Registers)
+	return c
+}
+
+// init resets registers which don't match the precision, e.g. if they are
+// missing in the stored data
+func (h *HyperLogLog) init() {
+	if len(h.Registers) != 1<<hyperLogLogPrecision {
+		h.Registers = make([]uint8, 1<<hyperLogLogPrecision)
+		h.counted = false
+	}
+}
+
+// mix spreads the bits of the given hash (finalizer of murmur3)
+func mix(x uint64) uint64 {
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
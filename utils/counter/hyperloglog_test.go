@@ -0,0 +1,52 @@
+package counter
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestHyperLogLogCount(t *testing.T) {
+	for _, n := range []int{0, 10, 100, 1000, 100000} {
+		h := NewHyperLogLog()
+		for i := 0; i < n; i++ {
+			h.Add(fmt.Sprintf("visitor-%d", i))
+			h.Add(fmt.Sprintf("visitor-%d", i))
+		}
+		count := h.Count()
+		if diff := float64(count - int64(n)); diff > 0.1*float64(n)+1 || -diff > 0.1*float64(n)+1 {
+			t.Errorf("estimated %d distinct values, expected %d", count, n)
+		}
+	}
+}
+
+func TestHyperLogLogMerge(t *testing.T) {
+	a, b := NewHyperLogLog(), NewHyperLogLog()
+	for i := 0; i < 200; i++ {
+		a.Add(fmt.Sprintf("visitor-%d", i))
+		b.Add(fmt.Sprintf("visitor-%d", i+100))
+	}
+	a.Merge(b)
+	if count := a.Count(); count < 290 || count > 310 {
+		t.Errorf("estimated %d distinct values after merge, expected 300", count)
+	}
+}
+
+func TestUniqueVisitorsAreStored(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	c := NewCounterWithStore(time.Hour, NewFileStore(dir))
+	c.AddEntry(c.GetSection("user", "repository"), NewEntry("visitor"))
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	c = NewCounterWithStore(time.Hour, NewFileStore(dir))
+	section := c.GetSection("user", "repository")
+	c.AddEntry(section, NewEntry("visitor"))
+	if unique := section.GetMetric(MetricUnique); unique != 1 {
+		t.Errorf("counted %d unique visitors, expected 1", unique)
+	}
+}
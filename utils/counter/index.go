@@ -0,0 +1,160 @@
+package counter
+
+import (
+	"../log"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// NewIndex loads the index of the given store. The index is rebuilt from the
+// stored sections if it isn't stored yet.
+func NewIndex(store Store) *Index {
+	i := &Index{
+		Children: make(map[string]map[string]bool),
+		store:    store,
+		mx:       &sync.RWMutex{},
+	}
+
+	children, err := store.LoadIndex()
+	if err == nil {
+		i.Children = children
+	}
+	if err != nil {
+		if err != ErrNotStored {
+			log.Error(err)
+		}
+		i.rebuild(store)
+	}
+	if i.Children == nil {
+		i.Children = make(map[string]map[string]bool)
+	}
+	return i
+}
+
+// SectionKey returns the key of the given section or page
+func SectionKey(username string, repository string, page string) string {
+	key := username + "/" + repository
+	if page != "" {
+		key += "/" + page
+	}
+	return key
+}
+
+// splitKey splits the given key into username, repository and page
+func splitKey(sectionKey string) (string, string, string) {
+	parts := strings.SplitN(sectionKey, "/", 3)
+	for len(parts) < 3 {
+		parts = append(parts, "")
+	}
+	return parts[0], parts[1], parts[2]
+}
+
+// Add adds the given section to the index
+func (i *Index) Add(username string, repository string, page string) {
+	i.mx.Lock()
+	defer i.mx.Unlock()
+
+	i.add(username, SectionKey(username, repository, ""))
+	if page != "" {
+		i.add(SectionKey(username, repository, ""), SectionKey(username, repository, page))
+	}
+}
+
+func (i *Index) add(parent string, child string) {
+	children, ok := i.Children[parent]
+	if !ok {
+		children = make(map[string]bool)
+		i.Children[parent] = children
+	}
+	if !children[child] {
+		children[child] = true
+		i.dirty = true
+	}
+}
+
+// Remove removes the given section from the index. Removing a repository
+// doesn't remove its pages.
+func (i *Index) Remove(username string, repository string, page string) {
+	i.mx.Lock()
+	defer i.mx.Unlock()
+
+	parent, child := username, SectionKey(username, repository, "")
+	if page != "" {
+		parent, child = child, SectionKey(username, repository, page)
+	}
+	if children, ok := i.Children[parent]; ok && children[child] {
+		delete(children, child)
+		if len(children) == 0 {
+			delete(i.Children, parent)
+		}
+		i.dirty = true
+	}
+}
+
+// Get returns the keys of all children of the given owner (username) or
+// repository (username/repository)
+func (i *Index) Get(parent string) []string {
+	i.mx.RLock()
+	defer i.mx.RUnlock()
+
+	keys := make([]string, 0, len(i.Children[parent]))
+	for key := range i.Children[parent] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Has reports whether the given section is indexed
+func (i *Index) Has(username string, repository string, page string) bool {
+	i.mx.RLock()
+	defer i.mx.RUnlock()
+
+	parent, child := username, SectionKey(username, repository, "")
+	if page != "" {
+		parent, child = child, SectionKey(username, repository, page)
+	}
+	return i.Children[parent][child]
+}
+
+// Count returns the number of children of the given owner or repository
+func (i *Index) Count(parent string) int {
+	i.mx.RLock()
+	defer i.mx.RUnlock()
+	return len(i.Children[parent])
+}
+
+// Save writes the index if it changed since it has been saved the last time
+func (i *Index) Save() error {
+	i.mx.Lock()
+	defer i.mx.Unlock()
+	if !i.dirty {
+		return nil
+	}
+
+	if err := i.store.SaveIndex(i.Children); err != nil {
+		return err
+	}
+	i.dirty = false
+	return nil
+}
+
+// rebuild adds all sections of the given store
+func (i *Index) rebuild(store Store) {
+	keys, err := store.List()
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	for _, key := range keys {
+		username, repository, page := splitKey(key)
+		i.add(username, SectionKey(username, repository, ""))
+		if page != "" {
+			i.add(SectionKey(username, repository, ""), key)
+		}
+	}
+	if len(keys) > 0 {
+		log.Info("Section index rebuilt")
+	}
+}
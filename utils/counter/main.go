@@ -1,22 +1,59 @@
 package counter
 
 import (
+	"../log"
+	"../metrics"
+	"container/list"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// Number of shards the loaded sections are spread across
+const shardCount = 32
+
+// NewCounter creates a counter storing its sections as json files in the
+// data directory
 func NewCounter(duration time.Duration) *Counter {
+	return NewCounterWithStore(duration, NewFileStore(dataDir()))
+}
+
+func NewCounterWithStore(duration time.Duration, store Store) *Counter {
 	c := &Counter{
-		Duration: duration,
-	}
-	if c.Sections == nil {
-		c.Sections = make(map[string]*Section)
+		Duration:         duration,
+		Index:            NewIndex(store),
+		Store:            store,
+		Policy:           DefaultPolicy(duration),
+		FlushInterval:    time.Minute,
+		FlushConcurrency: 4,
+		shards:           make([]*shard, shardCount),
+		ops:              &sync.Mutex{},
+		evictions:        make(map[string]int64),
+		evictionsMx:      &sync.Mutex{},
 	}
-	for k, _ := range c.Sections {
-		c.Sections[k].Entries = make(map[string]*Entry)
+	for i := range c.shards {
+		c.shards[i] = &shard{
+			mx:       &sync.Mutex{},
+			sections: make(map[string]*Section),
+			recent:   list.New(),
+			elements: make(map[string]*list.Element),
+			evicted:  make(map[string]*eviction),
+			loading:  make(map[string]chan bool),
+			shrinkMx: &sync.Mutex{},
+			stored:   make(map[string]*storedTotal),
+		}
 	}
 	return c
 }
 
+// shard returns the shard the given section belongs to
+func (c *Counter) shard(sectionKey string) *shard {
+	h := fnv.New32a()
+	h.Write([]byte(sectionKey))
+	return c.shards[h.Sum32()%uint32(len(c.shards))]
+}
+
 func NewEntry(hash string) *Entry {
 	c := &Entry{
 		Hash:      hash,
@@ -26,72 +63,453 @@ func NewEntry(hash string) *Entry {
 }
 
 func (c *Counter) GetSection(username string, repository string) *Section {
-	sectionKey := username + "/" + repository
-	if _, ok := c.Sections[sectionKey]; !ok {
-		c.Sections[sectionKey] = NewSection(username, repository)
+	return c.GetPage(username, repository, "")
+}
+
+// GetPage returns the section of a page below the given repository or the
+// repository itself if page is empty. Sections are loaded from the store
+// without locking their shard, concurrent requests of the same section wait
+// for the first one to load it.
+func (c *Counter) GetPage(username string, repository string, page string) *Section {
+	sectionKey := SectionKey(username, repository, page)
+	sh := c.shard(sectionKey)
+
+	for {
+		sh.mx.Lock()
+		if section, ok := sh.lookup(sectionKey); ok {
+			full := c.touch(sh, sectionKey, section)
+			sh.mx.Unlock()
+			c.shrink(sh, full)
+			return section
+		}
+		if done, ok := sh.loading[sectionKey]; ok {
+			sh.mx.Unlock()
+			<-done
+			continue
+		}
+		done := make(chan bool)
+		sh.loading[sectionKey] = done
+		sh.mx.Unlock()
+
+		section := c.newPage(username, repository, page)
+
+		sh.mx.Lock()
+		delete(sh.loading, sectionKey)
+		if current, ok := sh.lookup(sectionKey); ok {
+			section = current
+		} else {
+			sh.insert(sectionKey, section)
+			c.Index.Add(username, repository, page)
+		}
+		full := c.touch(sh, sectionKey, section)
+		sh.mx.Unlock()
+		close(done)
+		c.shrink(sh, full)
+		return section
 	}
-	return c.Sections[sectionKey]
 }
 
-func (c *Counter) GetSectionByKey(sectionKey string) *Section {
-	if _, ok := c.Sections[sectionKey]; !ok {
-		return nil
+// newPage creates the given section and loads its stored data
+func (c *Counter) newPage(username string, repository string, page string) *Section {
+	section := NewPage(username, repository, page)
+	section.store = c.Store
+	if err := section.Load(); err != nil {
+		log.Error(err)
 	}
-	return c.Sections[sectionKey]
+	return section
+}
+
+// GetSectionByKey returns the loaded section of the given key or nil. Evicted
+// sections are returned as well, since they may not have been saved yet.
+func (c *Counter) GetSectionByKey(sectionKey string) *Section {
+	sh := c.shard(sectionKey)
+
+	sh.mx.Lock()
+	defer sh.mx.Unlock()
+	return sh.loaded(sectionKey)
 }
 
 func (c *Counter) Run() {
+	interval := c.FlushInterval
+	if interval <= 0 {
+		interval = c.Duration
+	}
 	t := time.NewTicker(c.Duration)
+	f := time.NewTicker(interval)
 	defer func() {
 		t.Stop()
+		f.Stop()
 	}()
 
 	for {
 		select {
+		case <-f.C:
+			c.Flush()
 		case <-t.C:
-			for sectionKey, section := range c.Sections {
-				// Delete possible junk sections
-				if section.Total == 1 && time.Now().After(section.CreatedAt.Add(24*time.Hour)) {
-					c.RemoveSection(sectionKey)
-				}else{
-					_ = section.Save()
-					if time.Now().After(section.UpdatedAt.Add(c.Duration)) {
-						c.RemoveSection(sectionKey)
-					}
-				}
-				for hash, entry := range section.Entries {
-					if time.Now().After(entry.Timestamp.Add(c.Duration)) {
-						c.RemoveEntry(section, hash)
-					}
-				}
+			now := time.Now()
+			for _, sh := range c.shards {
+				c.sweep(sh, now)
 			}
+			c.reportEvictions()
 		}
 	}
 }
 
-func (c *Counter) RemoveEntry(section *Section, hash string) {
-	if _, ok := section.Entries[hash]; ok {
-		delete(section.Entries, hash)
+// Flush saves the index and all sections which changed since they have been
+// saved the last time. At most FlushConcurrency sections are written at the
+// same time.
+func (c *Counter) Flush() {
+	flushes := atomic.AddUint64(&c.flushes, 1)
+	if err := c.Index.Save(); err != nil {
+		log.Error(err)
+	}
+
+	var dirty []*Section
+	for _, sh := range c.shards {
+		sh.mx.Lock()
+		for _, section := range sh.sections {
+			if section.isDirty() {
+				dirty = append(dirty, section)
+			}
+		}
+		for _, e := range sh.evicted {
+			if e.section.isDirty() {
+				dirty = append(dirty, e.section)
+			}
+		}
+		sh.mx.Unlock()
+	}
+
+	workers := c.FlushConcurrency
+	if workers < 1 {
+		workers = 1
+	}
+	sem := make(chan bool, workers)
+	wg := &sync.WaitGroup{}
+	for _, section := range dirty {
+		sem <- true
+		wg.Add(1)
+		go func(section *Section) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := section.flush(); err != nil {
+				log.Error(err)
+				return
+			}
+			metrics.Add("sections_flushed_total", 1)
+		}(section)
+	}
+	wg.Wait()
+
+	for _, sh := range c.shards {
+		sh.drop(flushes)
+	}
+}
+
+// Close saves all pending changes and closes the store
+func (c *Counter) Close() error {
+	c.Flush()
+	return c.Store.Close()
+}
+
+// sweep expires the entries of all sections of the given shard and unloads
+// the ones which are junk or idle. Idle sections are saved before. The shard
+// is only locked while its sections are collected, hence hits aren't blocked
+// by the disk.
+func (c *Counter) sweep(sh *shard, now time.Time) {
+	sh.mx.Lock()
+	sections := make(map[string]*Section, len(sh.sections))
+	for sectionKey, section := range sh.sections {
+		sections[sectionKey] = section
+	}
+	sh.mx.Unlock()
+
+	for sectionKey, section := range sections {
+		section.expire(now, c.Duration)
+
+		// Delete possible junk sections
+		if c.Policy.isJunk(section, now) {
+			c.discard(sectionKey, section, now)
+			continue
+		}
+		if !c.Policy.isIdle(section, now) {
+			continue
+		}
+		if err := section.flush(); err != nil {
+			log.Error(err)
+			continue
+		}
+		c.unload(sectionKey, section, now)
 	}
 }
 
+func (c *Counter) RemoveEntry(section *Section, hash string) {
+	section.mx.Lock()
+	defer section.mx.Unlock()
+	delete(section.Entries, hash)
+}
+
 func (c *Counter) RemoveSection(sectionKey string) {
-	if _, ok := c.Sections[sectionKey]; ok {
-		delete(c.Sections, sectionKey)
+	sh := c.shard(sectionKey)
+
+	sh.mx.Lock()
+	defer sh.mx.Unlock()
+	sh.remove(sectionKey)
+}
+
+// insert loads the given section, the shard has to be locked
+func (sh *shard) insert(sectionKey string, section *Section) {
+	sh.sections[sectionKey] = section
+	delete(sh.stored, sectionKey)
+	sh.loads++
+}
+
+// loaded returns the loaded or evicted section of the given key without
+// touching it, the shard has to be locked
+func (sh *shard) loaded(sectionKey string) *Section {
+	if section, ok := sh.sections[sectionKey]; ok {
+		return section
+	}
+	if e, ok := sh.evicted[sectionKey]; ok {
+		return e.section
+	}
+	return nil
+}
+
+// remove unloads the given section, the shard has to be locked
+func (sh *shard) remove(sectionKey string) {
+	delete(sh.sections, sectionKey)
+	delete(sh.evicted, sectionKey)
+	if element, ok := sh.elements[sectionKey]; ok {
+		sh.recent.Remove(element)
+		delete(sh.elements, sectionKey)
+	}
+}
+
+// evict unloads the given section but keeps it until it has been saved, the
+// shard has to be locked
+func (sh *shard) evict(sectionKey string, flushes uint64) {
+	if section, ok := sh.sections[sectionKey]; ok {
+		sh.evicted[sectionKey] = &eviction{section: section, flushes: flushes}
+	}
+	delete(sh.sections, sectionKey)
+	if element, ok := sh.elements[sectionKey]; ok {
+		sh.recent.Remove(element)
+		delete(sh.elements, sectionKey)
 	}
 }
 
+// lookup returns the loaded section of the given key, evicted sections are
+// loaded again. Closed sections are dropped, they must not receive hits
+// anymore. The shard has to be locked.
+func (sh *shard) lookup(sectionKey string) (*Section, bool) {
+	section, ok := sh.sections[sectionKey]
+	if !ok {
+		section, ok = sh.revive(sectionKey)
+	}
+	if ok && section.isClosed() {
+		sh.remove(sectionKey)
+		return nil, false
+	}
+	return section, ok
+}
+
+// block keeps the given section from being loaded until the returned
+// function is called, requests of the section wait meanwhile. A section
+// which is loaded already is still returned.
+func (c *Counter) block(sectionKey string) func() {
+	sh := c.shard(sectionKey)
+
+	sh.mx.Lock()
+	for {
+		done, ok := sh.loading[sectionKey]
+		if !ok {
+			break
+		}
+		sh.mx.Unlock()
+		<-done
+		sh.mx.Lock()
+	}
+	done := make(chan bool)
+	sh.loading[sectionKey] = done
+	sh.mx.Unlock()
+
+	return func() {
+		sh.mx.Lock()
+		delete(sh.loading, sectionKey)
+		sh.mx.Unlock()
+		close(done)
+	}
+}
+
+// hold blocks the given key like block and returns its section, which is
+// loaded if necessary. The section isn't closed by evictions until the
+// returned function is called, which releases the key as well.
+func (c *Counter) hold(sectionKey string) (*Section, func()) {
+	release := c.block(sectionKey)
+	sh := c.shard(sectionKey)
+
+	sh.mx.Lock()
+	section, ok := sh.lookup(sectionKey)
+	if !ok {
+		sh.mx.Unlock()
+		section = c.newPage(splitKey(sectionKey))
+		sh.mx.Lock()
+		if current, ok := sh.lookup(sectionKey); ok {
+			section = current
+		} else {
+			sh.insert(sectionKey, section)
+			c.Index.Add(splitKey(sectionKey))
+		}
+	}
+	section.pin()
+	full := c.touch(sh, sectionKey, section)
+	sh.mx.Unlock()
+	c.shrink(sh, full)
+
+	return section, func() {
+		section.unpin()
+		release()
+	}
+}
+
+// revive loads an evicted section again, the shard has to be locked
+func (sh *shard) revive(sectionKey string) (*Section, bool) {
+	e, ok := sh.evicted[sectionKey]
+	if !ok {
+		return nil, false
+	}
+	delete(sh.evicted, sectionKey)
+	sh.sections[sectionKey] = e.section
+	return e.section, true
+}
+
+// drop releases the evicted sections which have been saved. Sections are
+// kept for at least one full flush after their eviction and closed once
+// they are dropped, so hits of requests which were in progress meanwhile
+// aren't lost.
+func (sh *shard) drop(flushes uint64) {
+	sh.mx.Lock()
+	defer sh.mx.Unlock()
+	for sectionKey, e := range sh.evicted {
+		if flushes > e.flushes+1 && e.section.closeSaved() {
+			delete(sh.evicted, sectionKey)
+		}
+	}
+}
+
+// shrink saves and drops the evicted sections of the given shard if there
+// are more of them than loaded sections are allowed per shard, which is
+// reported by touch. Requests evicting sections faster than they are flushed
+// wait for the store hence, instead of filling up the memory. Dropped
+// sections are closed, so requests still holding them load them again.
+func (c *Counter) shrink(sh *shard, full bool) {
+	if !full {
+		return
+	}
+	sh.shrinkMx.Lock()
+	defer sh.shrinkMx.Unlock()
+
+	sh.mx.Lock()
+	if len(sh.evicted) <= c.Policy.perShard(len(c.shards)) {
+		sh.mx.Unlock()
+		return
+	}
+	evicted := make(map[string]*eviction, len(sh.evicted))
+	for sectionKey, e := range sh.evicted {
+		evicted[sectionKey] = e
+	}
+	sh.mx.Unlock()
+
+	for sectionKey, e := range evicted {
+		if err := e.section.flush(); err != nil {
+			log.Error(err)
+			continue
+		}
+		sh.mx.Lock()
+		if sh.evicted[sectionKey] == e && e.section.closeSaved() {
+			delete(sh.evicted, sectionKey)
+		}
+		sh.mx.Unlock()
+	}
+}
+
+// put loads the given section under the given key
+func (c *Counter) put(sectionKey string, section *Section) {
+	sh := c.shard(sectionKey)
+
+	sh.mx.Lock()
+	sh.insert(sectionKey, section)
+	full := c.touch(sh, sectionKey, section)
+	sh.mx.Unlock()
+	c.shrink(sh, full)
+}
+
+// AddEntry counts the given entry for the loaded section of the key of the
+// given section. The given section is loaded again if it has been unloaded
+// meanwhile, unless it has been closed. A closed section is replaced by the
+// stored one.
 func (c *Counter) AddEntry(section *Section, entry *Entry) bool {
+	added := false
+	c.apply(section, func(section *Section) bool {
+		var ok bool
+		added, ok = section.addEntry(entry, c.Duration)
+		return ok
+	})
+	return added
+}
+
+// apply calls the given function with the loaded section of the key of the
+// given section until it reports that the section hasn't been closed
+func (c *Counter) apply(section *Section, f func(section *Section) bool) {
+	for {
+		section = c.resident(section)
+		if f(section) {
+			return
+		}
+	}
+}
+
+// resident returns the loaded section of the key of the given section
+func (c *Counter) resident(section *Section) *Section {
 	sectionKey := section.GetKey()
+	sh := c.shard(sectionKey)
 
-	if _, ok := c.Sections[sectionKey]; !ok {
-		c.Sections[sectionKey] = section
+	sh.mx.Lock()
+	if current, ok := sh.lookup(sectionKey); ok {
+		full := c.touch(sh, sectionKey, current)
+		sh.mx.Unlock()
+		c.shrink(sh, full)
+		return current
+	}
+	if !section.isClosed() {
+		sh.insert(sectionKey, section)
+		full := c.touch(sh, sectionKey, section)
+		sh.mx.Unlock()
+		c.shrink(sh, full)
+		return section
 	}
-	result := c.Sections[sectionKey].AddEntry(entry, c.Duration)
+	sh.mx.Unlock()
 
-	return result
+	return c.GetPage(splitKey(sectionKey))
 }
 
+// Increment counts a hit for the loaded section of the key of the given
+// section, see AddEntry
 func (c *Counter) Increment(section *Section) {
-	section.Increment()
-}
\ No newline at end of file
+	c.IncrementBy(section, 1)
+}
+
+func (c *Counter) IncrementBy(section *Section, n int64) {
+	c.apply(section, func(section *Section) bool {
+		return section.increment(n)
+	})
+}
+
+// IncrementBot counts a bot hit for the loaded section of the key of the
+// given section, see AddEntry
+func (c *Counter) IncrementBot(section *Section) {
+	c.apply(section, (*Section).IncrementBot)
+}
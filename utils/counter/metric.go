@@ -0,0 +1,152 @@
+package counter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	MetricTotal  = "total"
+	MetricToday  = "today"
+	MetricWeek   = "week"
+	MetricMonth  = "month"
+	MetricUnique = "unique"
+)
+
+// Default badge labels of the available metrics
+var MetricLabels = map[string]string{
+	MetricTotal:  "hits",
+	MetricToday:  "hits today",
+	MetricWeek:   "hits 7d",
+	MetricMonth:  "hits 30d",
+	MetricUnique: "visitors today",
+}
+
+// ParseMetric returns the given metric if it's known and MetricTotal otherwise
+func ParseMetric(in string) string {
+	in = strings.ToLower(in)
+	if _, ok := MetricLabels[in]; ok {
+		return in
+	}
+	return MetricTotal
+}
+
+// GetMetric returns the value of the given metric. The period based metrics
+// use the daily hit buckets: "today" is the current UTC day, "week" and
+// "month" the last 7 and 30 days including today.
+func (s *Section) GetMetric(metric string) int64 {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	return s.getMetric(metric)
+}
+
+func (s *Section) getMetric(metric string) int64 {
+	now := time.Now()
+	switch metric {
+	case MetricToday:
+		return s.sumDays(now, 1)
+	case MetricWeek:
+		return s.sumDays(now, 7)
+	case MetricMonth:
+		return s.sumDays(now, 30)
+	case MetricUnique:
+		if s.Unique == nil || !s.Unique.Start.Equal(truncate(now, GranularityDay)) {
+			return 0
+		}
+		return s.Unique.Hits
+	}
+	return s.Total
+}
+
+// GetStats returns a snapshot of the section, which stays consistent while
+// it's encoded even if the section receives further hits
+func (s *Section) GetStats(metric string) *Stats {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	stats := &Stats{Section: s.snapshot()}
+	if metric != "" {
+		stats.Metric = ParseMetric(metric)
+		stats.Value = s.getMetric(stats.Metric)
+	}
+	return stats
+}
+
+// snapshot copies the fields of the section which are part of its stats
+func (s *Section) snapshot() *Section {
+	return &Section{
+		Username:   s.Username,
+		Repository: s.Repository,
+		Page:       s.Page,
+		Total:      s.Total,
+		BotTotal:   s.BotTotal,
+		CreatedAt:  s.CreatedAt,
+		UpdatedAt:  s.UpdatedAt,
+	}
+}
+
+func (s *Stats) String() string {
+	if s.Metric == "" {
+		return s.Section.String()
+	}
+	return strings.Join([]string{
+		s.Section.String(),
+		s.Metric,
+		fmt.Sprintf("%d", s.Value),
+	}, ",")
+}
+
+// trackVisitor counts the visitor identified by the given hash as unique
+// visitor if it hasn't been seen during the current day. The visitors are
+// kept in a HyperLogLog which is stored along with the section, hence the
+// number is an estimate, though small numbers are almost exact.
+func (s *Section) trackVisitor(hash string, t time.Time) {
+	day := truncate(t, GranularityDay)
+	if s.Unique == nil || !s.Unique.Start.Equal(day) {
+		s.Unique = &Period{Start: day}
+	}
+	if s.Unique.Visitors == nil {
+		s.Unique.Visitors = NewHyperLogLog()
+	}
+	if s.Unique.Visitors.Add(hash) {
+		s.changed()
+		s.Unique.count(s.Unique.Visitors.Count())
+	}
+}
+
+// Merge adds the unique visitors of the given period of the same day.
+// Visitors of both periods are counted once if both of them are known.
+func (p *Period) Merge(other *Period) {
+	if p.Visitors == nil || other.Visitors == nil {
+		p.Hits += other.Hits
+		return
+	}
+	p.Visitors.Merge(other.Visitors)
+	p.count(other.Hits)
+	p.count(p.Visitors.Count())
+}
+
+// count raises the hits to the given estimate. Estimates of data stored
+// without visitors start from zero, hence the hits never decrease.
+func (p *Period) count(hits int64) {
+	if hits > p.Hits {
+		p.Hits = hits
+	}
+}
+
+func (p *Period) copy() *Period {
+	c := &Period{Start: p.Start, Hits: p.Hits}
+	if p.Visitors != nil {
+		c.Visitors = p.Visitors.copy()
+	}
+	return c
+}
+
+func (s *Section) sumDays(now time.Time, days int) int64 {
+	total := int64(0)
+	day := truncate(now, GranularityDay)
+	for i := 0; i < days; i++ {
+		total += s.History.Get(day.AddDate(0, 0, -i), GranularityDay)
+	}
+	return total
+}
@@ -0,0 +1,308 @@
+package counter
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidKey      = errors.New("invalid section, expected username/repository")
+	ErrSectionNotFound = errors.New("section not found")
+	ErrSectionExists   = errors.New("section already exists")
+	ErrSameSection     = errors.New("source and target are the same section")
+	ErrNegativeTotal   = errors.New("total must not be negative")
+	ErrSectionClosed   = errors.New("section has been closed")
+)
+
+var validKeyPart = regexp.MustCompile(`^[a-zA-Z0-9\-_.]+$`)
+
+// ParseKey splits the given section key into username and repository
+func ParseKey(sectionKey string) (string, string, error) {
+	parts := strings.Split(sectionKey, "/")
+	if len(parts) != 2 || !validKeyPart.MatchString(parts[0]) || !validKeyPart.MatchString(parts[1]) {
+		return "", "", ErrInvalidKey
+	}
+	return parts[0], parts[1], nil
+}
+
+// HasSection reports whether the given section is loaded or stored. Evicted
+// sections count as loaded, since new ones aren't stored until they are
+// flushed.
+func (c *Counter) HasSection(sectionKey string) bool {
+	sh := c.shard(sectionKey)
+	sh.mx.Lock()
+	section := sh.loaded(sectionKey)
+	sh.mx.Unlock()
+	if section != nil {
+		return true
+	}
+	_, err := c.Store.Load(sectionKey)
+	return err == nil
+}
+
+// Rename moves a repository including its totals, history and pages to a
+// new key. The target must not exist yet.
+func (c *Counter) Rename(from string, to string) (*Section, error) {
+	c.ops.Lock()
+	defer c.ops.Unlock()
+	pages, err := c.prepare(from, to)
+	if err != nil {
+		return nil, err
+	}
+	if c.HasSection(to) {
+		return nil, ErrSectionExists
+	}
+	for _, page := range pages {
+		if c.HasSection(to + strings.TrimPrefix(page, from)) {
+			return nil, ErrSectionExists
+		}
+	}
+
+	if c.HasSection(from) {
+		if err := c.move(from, to); err != nil {
+			return nil, err
+		}
+	}
+	for _, page := range pages {
+		if err := c.move(page, to+strings.TrimPrefix(page, from)); err != nil {
+			return nil, err
+		}
+	}
+	c.Index.Remove(splitKey(from))
+	return c.loadSection(to)
+}
+
+// Merge adds all data of the repository from, including its pages, to the
+// repository into and removes the first one afterwards. The target is
+// created if it doesn't exist.
+func (c *Counter) Merge(from string, into string) (*Section, error) {
+	c.ops.Lock()
+	defer c.ops.Unlock()
+	pages, err := c.prepare(from, into)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.HasSection(from) {
+		if err := c.merge(from, into); err != nil {
+			return nil, err
+		}
+	}
+	for _, page := range pages {
+		if err := c.merge(page, into+strings.TrimPrefix(page, from)); err != nil {
+			return nil, err
+		}
+	}
+	c.Index.Remove(splitKey(from))
+	return c.loadSection(into)
+}
+
+// prepare validates the repositories of a rename or merge and returns the
+// pages of the source
+func (c *Counter) prepare(from string, to string) ([]string, error) {
+	if _, _, err := ParseKey(from); err != nil {
+		return nil, err
+	}
+	if _, _, err := ParseKey(to); err != nil {
+		return nil, err
+	}
+	if from == to {
+		return nil, ErrSameSection
+	}
+
+	pages := c.Index.Get(from)
+	if !c.HasSection(from) && len(pages) == 0 {
+		return nil, ErrSectionNotFound
+	}
+	return pages, nil
+}
+
+// move stores the section from under the key to. Both keys are blocked
+// until the old section has been deleted from the store, hence concurrent
+// requests can't load it again or create the new one in the meantime. The
+// section is held meanwhile, so it can't be closed by an eviction.
+func (c *Counter) move(from string, to string) error {
+	section, release := c.hold(from)
+	defer release()
+	releaseTo := c.block(to)
+	defer releaseTo()
+
+	section.mx.Lock()
+	section.Username, section.Repository, section.Page = splitKey(to)
+	section.changed()
+	section.mx.Unlock()
+	if err := section.Save(); err != nil {
+		return err
+	}
+
+	c.RemoveSection(from)
+	c.put(to, section)
+	c.Index.Remove(splitKey(from))
+	c.Index.Add(splitKey(to))
+
+	return c.Store.Delete(from)
+}
+
+// merge adds the section from to the section into and removes it. The source
+// is closed before, so it can't receive hits which wouldn't be merged, and
+// its key is blocked until it has been deleted from the store. Both sections
+// are held meanwhile, so they can't be closed by an eviction.
+func (c *Counter) merge(from string, into string) error {
+	source, release := c.hold(from)
+	defer release()
+	target, releaseInto := c.hold(into)
+	defer releaseInto()
+
+	source.close()
+	c.RemoveSection(from)
+	target.Merge(source)
+	if err := target.Save(); err != nil {
+		return err
+	}
+	c.Index.Remove(splitKey(from))
+
+	return c.Store.Delete(from)
+}
+
+// Update applies the given update to the loaded section of the key of the
+// given section and returns it. The update is applied to the loaded section
+// again if it returns ErrSectionClosed, see AddEntry.
+func (c *Counter) Update(section *Section, update func(section *Section) error) (*Section, error) {
+	for {
+		section = c.resident(section)
+		if err := update(section); err != ErrSectionClosed {
+			return section, err
+		}
+	}
+}
+
+// SetTotal overrides the total of the section. The history remains as it is.
+// ErrSectionClosed is returned if the section has been closed.
+func (s *Section) SetTotal(total int64) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	return s.setTotal(total)
+}
+
+func (s *Section) setTotal(total int64) error {
+	if s.closed {
+		return ErrSectionClosed
+	}
+	if total < 0 {
+		return ErrNegativeTotal
+	}
+	s.changed()
+	s.Total = total
+	s.UpdatedAt = time.Now()
+	return nil
+}
+
+// AdjustTotal adds the given delta to the total of the section like SetTotal.
+// The history remains as it is.
+func (s *Section) AdjustTotal(delta int64) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	return s.setTotal(s.Total + delta)
+}
+
+// Reset discards all hits, the history and all breakdowns of the section.
+// ErrSectionClosed is returned if the section has been closed.
+func (s *Section) Reset() error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.closed {
+		return ErrSectionClosed
+	}
+	s.changed()
+	s.Total = 0
+	s.BotTotal = 0
+	s.UpdatedAt = time.Now()
+	s.History = NewHistory()
+	s.Unique = nil
+	s.Referrers = nil
+	s.Countries = nil
+	s.Clients = nil
+	s.Entries = make(map[string]*Entry)
+	return nil
+}
+
+func (c *Counter) loadSection(sectionKey string) (*Section, error) {
+	username, repository, err := ParseKey(sectionKey)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetSection(username, repository), nil
+}
+
+// Merge adds the totals, history and breakdowns of the given section
+func (s *Section) Merge(other *Section) {
+	if other == s {
+		return
+	}
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	other.mx.RLock()
+	defer other.mx.RUnlock()
+	s.changed()
+	s.Total += other.Total
+	s.BotTotal += other.BotTotal
+	if other.CreatedAt.Before(s.CreatedAt) {
+		s.CreatedAt = other.CreatedAt
+	}
+	if other.UpdatedAt.After(s.UpdatedAt) {
+		s.UpdatedAt = other.UpdatedAt
+	}
+
+	if other.History != nil {
+		if s.History == nil {
+			s.History = NewHistory()
+		}
+		s.History.Merge(other.History)
+	}
+
+	if other.Unique != nil {
+		switch {
+		case s.Unique == nil || other.Unique.Start.After(s.Unique.Start):
+			s.Unique = other.Unique.copy()
+		case other.Unique.Start.Equal(s.Unique.Start):
+			s.Unique.Merge(other.Unique)
+		}
+	}
+
+	if other.Referrers != nil {
+		if s.Referrers == nil {
+			s.Referrers = NewTopK()
+		}
+		s.Referrers.Merge(other.Referrers)
+	}
+
+	for country, hits := range other.Countries {
+		if s.Countries == nil {
+			s.Countries = make(map[string]int64)
+		}
+		s.Countries[country] += hits
+	}
+
+	if other.Clients != nil {
+		if s.Clients == nil {
+			s.Clients = NewClients()
+		}
+		mergeHits(s.Clients.Browsers, other.Clients.Browsers)
+		mergeHits(s.Clients.OperatingSystems, other.Clients.OperatingSystems)
+		mergeHits(s.Clients.Devices, other.Clients.Devices)
+	}
+
+	for hash, entry := range other.Entries {
+		if existing, ok := s.Entries[hash]; !ok || entry.Timestamp.After(existing.Timestamp) {
+			s.Entries[hash] = entry
+		}
+	}
+}
+
+func mergeHits(target map[string]int64, source map[string]int64) {
+	for name, hits := range source {
+		target[name] += hits
+	}
+}
@@ -0,0 +1,257 @@
+package counter
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestRename(t *testing.T) {
+	for _, backend := range []string{StorageJSON, StorageBolt} {
+		t.Run(backend, func(t *testing.T) {
+			dir := tempDir(t)
+			defer os.RemoveAll(dir)
+
+			c := NewCounterWithStore(time.Hour, openStore(t, backend, dir))
+			c.IncrementBy(c.GetPage("user", "old", ""), 3)
+			c.IncrementBy(c.GetPage("user", "old", "docs"), 2)
+			c.Flush()
+
+			if _, err := c.Rename("user/old", "user/new"); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			c = NewCounterWithStore(time.Hour, openStore(t, backend, dir))
+			defer c.Close()
+			for _, sectionKey := range []string{"user/old", "user/old/docs"} {
+				if _, err := c.Store.Load(sectionKey); err != ErrNotStored {
+					t.Errorf("%s is still stored: %v", sectionKey, err)
+				}
+			}
+			if c.Index.Has("user", "old", "") {
+				t.Error("user/old is still indexed")
+			}
+			if !c.Index.Has("user", "new", "docs") {
+				t.Error("user/new/docs isn't indexed")
+			}
+
+			assertTotal(t, c.GetPage("user", "new", ""), 3)
+			assertTotal(t, c.GetPage("user", "new", "docs"), 2)
+			now := time.Now()
+			if hits := reportedHits(c.GetHistory("user", "new", "", now, now, GranularityHour)); hits != 3 {
+				t.Errorf("reported %d hits, expected 3", hits)
+			}
+		})
+	}
+}
+
+func TestRenameExisting(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	c := NewCounterWithStore(time.Hour, NewFileStore(dir))
+	defer c.Close()
+	c.Increment(c.GetPage("user", "old", ""))
+	c.Increment(c.GetPage("user", "new", ""))
+
+	if _, err := c.Rename("user/old", "user/new"); err != ErrSectionExists {
+		t.Errorf("renamed to an existing section: %v", err)
+	}
+	if _, err := c.Rename("user/missing", "user/other"); err != ErrSectionNotFound {
+		t.Errorf("renamed a missing section: %v", err)
+	}
+	assertTotal(t, c.GetPage("user", "old", ""), 1)
+}
+
+func TestMerge(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	c := NewCounterWithStore(time.Hour, NewFileStore(dir))
+	source := c.GetPage("user", "old", "")
+	c.IncrementBy(source, 2)
+	c.AddReferrer(source, "github.com", 10)
+	c.AddCountry(source, "DE")
+	c.AddClient(source, "Firefox", "Linux", "desktop")
+	c.Increment(c.GetPage("user", "old", "docs"))
+
+	target := c.GetPage("user", "new", "")
+	c.IncrementBy(target, 3)
+	c.AddReferrer(target, "github.com", 10)
+	c.AddReferrer(target, "example.com", 10)
+	c.AddCountry(target, "DE")
+	c.AddCountry(target, "FR")
+	c.AddClient(target, "Firefox", "Windows", "desktop")
+	c.Flush()
+
+	if _, err := c.Merge("user/old", "user/new"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	c = NewCounterWithStore(time.Hour, NewFileStore(dir))
+	defer c.Close()
+	if _, err := c.Store.Load("user/old"); err != ErrNotStored {
+		t.Errorf("user/old is still stored: %v", err)
+	}
+
+	section := c.GetPage("user", "new", "")
+	assertTotal(t, section, 5)
+	assertTotal(t, c.GetPage("user", "new", "docs"), 1)
+	if n := section.Referrers.Items["github.com"].Hits; n != 2 {
+		t.Errorf("merged %d hits of github.com, expected 2", n)
+	}
+	if section.Countries["DE"] != 2 || section.Countries["FR"] != 1 {
+		t.Errorf("merged countries %v, expected DE:2 FR:1", section.Countries)
+	}
+	if section.Clients.Browsers["Firefox"] != 2 || section.Clients.OperatingSystems["Linux"] != 1 {
+		t.Errorf("merged clients %v, expected 2 Firefox and 1 Linux", section.Clients)
+	}
+}
+
+// TestMergeEvicted merges sections which are evicted meanwhile, they must not
+// be closed before the merge has been saved
+func TestMergeEvicted(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	c := NewCounterWithStore(time.Hour, NewFileStore(dir))
+	c.Policy.MaxSections = shardCount
+	repositories := sameShard(c, 3)
+	c.IncrementBy(c.GetPage("user", repositories[0], ""), 2)
+	c.IncrementBy(c.GetPage("user", repositories[1], ""), 3)
+	c.Flush()
+
+	source, release := c.hold(SectionKey("user", repositories[0], ""))
+	c.GetPage("user", repositories[2], "")
+	c.Flush()
+	c.Flush()
+	if source.isClosed() {
+		t.Error("held section has been closed")
+	}
+	release()
+
+	from, into := "user/"+repositories[0], "user/"+repositories[1]
+	if _, err := c.Merge(from, into); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	c = NewCounterWithStore(time.Hour, NewFileStore(dir))
+	defer c.Close()
+	assertTotal(t, c.GetPage("user", repositories[1], ""), 5)
+}
+
+func TestSetTotal(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	c := NewCounterWithStore(time.Hour, NewFileStore(dir))
+	section := c.GetPage("user", "repository", "")
+	c.IncrementBy(section, 2)
+	c.Flush()
+
+	if _, err := c.Update(section, func(section *Section) error {
+		return section.SetTotal(40)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Update(section, func(section *Section) error {
+		return section.AdjustTotal(2)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Update(section, func(section *Section) error {
+		return section.AdjustTotal(-43)
+	}); err != ErrNegativeTotal {
+		t.Errorf("adjusted the total below zero: %v", err)
+	}
+	c.Flush()
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	c = NewCounterWithStore(time.Hour, NewFileStore(dir))
+	defer c.Close()
+	assertTotal(t, c.GetPage("user", "repository", ""), 42)
+	now := time.Now()
+	if hits := reportedHits(c.GetHistory("user", "repository", "", now, now, GranularityHour)); hits != 2 {
+		t.Errorf("reported %d hits, expected the history to remain at 2", hits)
+	}
+}
+
+func TestReset(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	c := NewCounterWithStore(time.Hour, NewFileStore(dir))
+	defer c.Close()
+	section := c.GetPage("user", "repository", "")
+	c.IncrementBy(section, 2)
+	c.AddCountry(section, "DE")
+	c.IncrementBot(section)
+
+	if _, err := c.Update(section, (*Section).Reset); err != nil {
+		t.Fatal(err)
+	}
+	assertTotal(t, section, 0)
+	now := time.Now()
+	if section.BotTotal != 0 || section.Countries != nil || reportedHits(section.GetHistory(now, now, GranularityHour)) != 0 {
+		t.Error("section hasn't been reset completely")
+	}
+}
+
+// TestUpdateClosed applies updates of a closed section to the loaded one
+func TestUpdateClosed(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	c := NewCounterWithStore(time.Hour, NewFileStore(dir))
+	defer c.Close()
+	section := c.GetPage("user", "repository", "")
+	c.IncrementBy(section, 2)
+	c.Flush()
+	c.unload("user/repository", section, time.Now().Add(2*time.Hour))
+	if !section.isClosed() {
+		t.Fatal("idle section hasn't been closed")
+	}
+
+	if err := section.SetTotal(10); err != ErrSectionClosed {
+		t.Errorf("set the total of a closed section: %v", err)
+	}
+	if err := section.Save(); err != ErrSectionClosed {
+		t.Errorf("saved a closed section: %v", err)
+	}
+	loaded, err := c.Update(section, func(section *Section) error {
+		return section.AdjustTotal(1)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded == section {
+		t.Error("updated the closed section")
+	}
+	assertTotal(t, c.GetPage("user", "repository", ""), 3)
+}
+
+func assertTotal(t *testing.T, section *Section, total int64) {
+	t.Helper()
+	if n := section.GetMetric(MetricTotal); n != total {
+		t.Errorf("%s has %d hits, expected %d", section.GetKey(), n, total)
+	}
+}
+
+func reportedHits(report *HistoryReport) int64 {
+	hits := int64(0)
+	for _, bucket := range report.Buckets {
+		hits += bucket.Hits
+	}
+	return hits
+}
@@ -0,0 +1,134 @@
+package counter
+
+import (
+	"../log"
+	"../metrics"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	EvictionJunk = "junk"
+	EvictionIdle = "idle"
+	EvictionLRU  = "lru"
+)
+
+// DefaultPolicy discards sections with a single hit after a day and unloads
+// sections which haven't been requested within the given duration
+func DefaultPolicy(idleTTL time.Duration) *Policy {
+	return &Policy{
+		JunkMinHits: 2,
+		JunkAge:     24 * time.Hour,
+		IdleTTL:     idleTTL,
+	}
+}
+
+func (p *Policy) isJunk(section *Section, now time.Time) bool {
+	section.mx.RLock()
+	defer section.mx.RUnlock()
+	return section.Total < p.JunkMinHits && now.After(section.CreatedAt.Add(p.JunkAge))
+}
+
+func (p *Policy) isIdle(section *Section, now time.Time) bool {
+	accessed := time.Unix(0, atomic.LoadInt64(&section.accessed))
+	return p.IdleTTL > 0 && now.After(accessed.Add(p.IdleTTL))
+}
+
+// perShard returns the maximum number of loaded sections per shard. The
+// limit is split evenly, so the least recently used section of a shard is
+// unloaded first even if other shards hold older ones.
+func (p *Policy) perShard(shards int) int {
+	if p.MaxSections <= 0 {
+		return 0
+	}
+	return (p.MaxSections + shards - 1) / shards
+}
+
+// touch marks the given section as the most recently used one of its shard.
+// The least recently used sections are evicted if the limit is exceeded and
+// saved by the next flush. It returns true if the shard holds more evicted
+// sections than the limit, which have to be shrunk.
+// The shard has to be locked.
+func (c *Counter) touch(sh *shard, sectionKey string, section *Section) bool {
+	atomic.StoreInt64(&section.accessed, time.Now().UnixNano())
+	if element, ok := sh.elements[sectionKey]; ok {
+		sh.recent.MoveToFront(element)
+	} else {
+		sh.elements[sectionKey] = sh.recent.PushFront(sectionKey)
+	}
+
+	limit := c.Policy.perShard(len(c.shards))
+	for limit > 0 && len(sh.sections) > limit {
+		key := sh.recent.Back().Value.(string)
+		if key == sectionKey {
+			break
+		}
+		sh.evict(key, atomic.LoadUint64(&c.flushes))
+		c.evicted(EvictionLRU)
+	}
+	return limit > 0 && len(sh.evicted) > limit
+}
+
+// discard unloads a junk section and deletes its file if configured. The
+// section is kept if it has been replaced or received hits in the meantime.
+// Its key is blocked until the file has been deleted, so it can't be loaded
+// again before.
+func (c *Counter) discard(sectionKey string, section *Section, now time.Time) {
+	if c.Policy.JunkDeleteFiles {
+		release := c.block(sectionKey)
+		defer release()
+	}
+	sh := c.shard(sectionKey)
+	sh.mx.Lock()
+	if sh.sections[sectionKey] != section || !c.Policy.isJunk(section, now) {
+		sh.mx.Unlock()
+		return
+	}
+	sh.remove(sectionKey)
+	sh.mx.Unlock()
+
+	c.evicted(EvictionJunk)
+	if !c.Policy.JunkDeleteFiles {
+		return
+	}
+	section.close()
+
+	if err := c.Store.Delete(sectionKey); err != nil {
+		log.Error(err)
+	}
+	c.Index.Remove(splitKey(sectionKey))
+}
+
+// unload unloads the given section if it is idle and hasn't changed since it
+// has been saved. The section is closed, so requests which are still holding
+// it load it again instead of counting hits which would never be saved.
+func (c *Counter) unload(sectionKey string, section *Section, now time.Time) {
+	sh := c.shard(sectionKey)
+	sh.mx.Lock()
+	defer sh.mx.Unlock()
+	if sh.sections[sectionKey] != section || !c.Policy.isIdle(section, now) || !section.closeSaved() {
+		return
+	}
+	sh.remove(sectionKey)
+	c.evicted(EvictionIdle)
+}
+
+func (c *Counter) evicted(reason string) {
+	c.evictionsMx.Lock()
+	c.evictions[reason]++
+	c.evictionsMx.Unlock()
+	metrics.Add(`sections_evicted_total{reason="`+reason+`"}`, 1)
+}
+
+// reportEvictions logs the number of evictions since the last report
+func (c *Counter) reportEvictions() {
+	c.evictionsMx.Lock()
+	defer c.evictionsMx.Unlock()
+	if len(c.evictions) == 0 {
+		return
+	}
+	log.Info(fmt.Sprintf("Sections evicted: %d junk, %d idle, %d lru",
+		c.evictions[EvictionJunk], c.evictions[EvictionIdle], c.evictions[EvictionLRU]))
+	c.evictions = make(map[string]int64)
+}
@@ -0,0 +1,117 @@
+package counter
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestJunk(t *testing.T) {
+	for _, deleteFiles := range []bool{false, true} {
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+
+		c := NewCounterWithStore(time.Hour, NewFileStore(dir))
+		c.Policy.JunkDeleteFiles = deleteFiles
+		c.Policy.IdleTTL = 0
+		junk := c.GetPage("user", "junk", "")
+		c.Increment(junk)
+		c.IncrementBy(c.GetPage("user", "repository", ""), 2)
+		c.Flush()
+
+		for _, sh := range c.shards {
+			c.sweep(sh, time.Now().Add(25*time.Hour))
+		}
+		if c.GetSectionByKey("user/junk") != nil {
+			t.Error("junk section is still loaded")
+		}
+		if c.GetSectionByKey("user/repository") == nil {
+			t.Error("section with enough hits has been unloaded")
+		}
+		if _, err := c.Store.Load("user/junk"); deleteFiles != (err == ErrNotStored) {
+			t.Errorf("junk section stored with deletion set to %t: %v", deleteFiles, err)
+		}
+		if junk.isClosed() != deleteFiles {
+			t.Errorf("junk section closed with deletion set to %t: %t", deleteFiles, junk.isClosed())
+		}
+		if c.Index.Has("user", "junk", "") == deleteFiles {
+			t.Errorf("junk section indexed with deletion set to %t", deleteFiles)
+		}
+		if n := c.evictions[EvictionJunk]; n != 1 {
+			t.Errorf("reported %d junk evictions, expected 1", n)
+		}
+		if err := c.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestIdle(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	c := NewCounterWithStore(time.Hour, NewFileStore(dir))
+	defer c.Close()
+	c.Policy.IdleTTL = time.Minute
+	idle := c.GetPage("user", "idle", "")
+	c.IncrementBy(idle, 2)
+
+	sh := c.shard("user/idle")
+	c.sweep(sh, time.Now())
+	if c.GetSectionByKey("user/idle") != idle {
+		t.Fatal("section has been unloaded before it was idle")
+	}
+	c.sweep(sh, time.Now().Add(2*time.Minute))
+	if c.GetSectionByKey("user/idle") != nil || !idle.isClosed() {
+		t.Fatal("idle section hasn't been unloaded")
+	}
+	if n := c.evictions[EvictionIdle]; n != 1 {
+		t.Errorf("reported %d idle evictions, expected 1", n)
+	}
+
+	// The section has been saved before, hits of requests still holding it
+	// are counted for the loaded one
+	c.Increment(idle)
+	assertTotal(t, c.GetPage("user", "idle", ""), 3)
+}
+
+func TestLRU(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	c := NewCounterWithStore(time.Hour, NewFileStore(dir))
+	defer c.Close()
+	c.Policy.MaxSections = shardCount
+	repositories := sameShard(c, 3)
+	first := c.GetPage("user", repositories[0], "")
+	c.IncrementBy(first, 1)
+	c.IncrementBy(c.GetPage("user", repositories[1], ""), 2)
+
+	sh := c.shard(SectionKey("user", repositories[0], ""))
+	if len(sh.sections) != 1 || len(sh.evicted) != 1 {
+		t.Errorf("loaded %d and evicted %d sections, expected one each", len(sh.sections), len(sh.evicted))
+	}
+	if n := c.evictions[EvictionLRU]; n != 1 {
+		t.Errorf("reported %d lru evictions, expected 1", n)
+	}
+
+	// Evicted sections are kept for a full flush, then they are dropped
+	c.Flush()
+	if len(sh.evicted) != 1 || first.isClosed() {
+		t.Error("evicted section has been dropped by the first flush")
+	}
+	c.Flush()
+	if len(sh.evicted) != 0 || !first.isClosed() {
+		t.Error("evicted section hasn't been dropped by the second flush")
+	}
+
+	// More evicted sections than loaded ones are dropped right away
+	c.IncrementBy(c.GetPage("user", repositories[0], ""), 1)
+	c.IncrementBy(c.GetPage("user", repositories[2], ""), 3)
+	if len(sh.evicted) > 1 {
+		t.Errorf("kept %d evicted sections, expected at most 1", len(sh.evicted))
+	}
+	for i, total := range []int64{2, 2, 3} {
+		assertTotal(t, c.GetPage("user", repositories[i], ""), total)
+	}
+}
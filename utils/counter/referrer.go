@@ -0,0 +1,162 @@
+package counter
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+func NewTopK() *TopK {
+	return &TopK{
+		Items: make(map[string]*TopKItem),
+	}
+}
+
+// Add counts the given name. If the limit is reached, the least frequent
+// name is replaced and its count inherited as possible error.
+func (t *TopK) Add(name string, limit int) {
+	if t.Items == nil {
+		t.Items = make(map[string]*TopKItem)
+	}
+	if item, ok := t.Items[name]; ok {
+		item.Hits++
+		return
+	}
+	if len(t.Items) < limit {
+		t.Items[name] = &TopKItem{Hits: 1}
+		return
+	}
+
+	// Merged lists may exceed the limit
+	for len(t.Items) > limit {
+		minName, _ := t.min()
+		delete(t.Items, minName)
+	}
+
+	minName, min := t.min()
+	if min == nil {
+		return
+	}
+	delete(t.Items, minName)
+	t.Items[name] = &TopKItem{Hits: min.Hits + 1, Error: min.Hits}
+}
+
+// min returns the least frequent name
+func (t *TopK) min() (string, *TopKItem) {
+	var minName string
+	var min *TopKItem
+	for n, item := range t.Items {
+		if min == nil || item.Hits < min.Hits {
+			minName, min = n, item
+		}
+	}
+	return minName, min
+}
+
+// Merge adds the counts of the given list. The result is trimmed to the
+// limit by the next call of Add.
+func (t *TopK) Merge(other *TopK) {
+	if other == nil {
+		return
+	}
+	if t.Items == nil {
+		t.Items = make(map[string]*TopKItem)
+	}
+	for name, item := range other.Items {
+		if existing, ok := t.Items[name]; ok {
+			existing.Hits += item.Hits
+			existing.Error += item.Error
+		} else {
+			t.Items[name] = &TopKItem{Hits: item.Hits, Error: item.Error}
+		}
+	}
+}
+
+// Top returns all tracked names ordered by their hits
+func (t *TopK) Top() []*Referrer {
+	referrers := make([]*Referrer, 0, len(t.Items))
+	for name, item := range t.Items {
+		referrers = append(referrers, &Referrer{Name: name, Hits: item.Hits, Error: item.Error})
+	}
+	sort.Slice(referrers, func(i, j int) bool {
+		if referrers[i].Hits == referrers[j].Hits {
+			return referrers[i].Name < referrers[j].Name
+		}
+		return referrers[i].Hits > referrers[j].Hits
+	})
+	return referrers
+}
+
+// ParseReferrer returns the host of the given referer header, followed by
+// its path if requested. An empty string is returned for invalid values.
+func ParseReferrer(referer string, withPath bool) string {
+	u, err := url.Parse(strings.TrimSpace(referer))
+	if err != nil || u.Host == "" {
+		return ""
+	}
+
+	name := strings.ToLower(u.Hostname())
+	if withPath {
+		name += strings.TrimRight(u.EscapedPath(), "/")
+	}
+	if len(name) > 256 {
+		name = name[:256]
+	}
+	return name
+}
+
+// AddReferrer counts a hit of the given referrer for the loaded section of
+// the key of the given section, see AddEntry
+func (c *Counter) AddReferrer(section *Section, referrer string, limit int) {
+	c.apply(section, func(section *Section) bool {
+		return section.AddReferrer(referrer, limit)
+	})
+}
+
+// AddReferrer counts a hit of the given referrer, keeping at most limit
+// referrers. It returns false if the section has been closed.
+func (s *Section) AddReferrer(referrer string, limit int) bool {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.closed {
+		return false
+	}
+	if referrer == "" || limit <= 0 {
+		return true
+	}
+	s.changed()
+	if s.Referrers == nil {
+		s.Referrers = NewTopK()
+	}
+	s.Referrers.Add(referrer, limit)
+	return true
+}
+
+func (s *Section) GetReferrers() *ReferrerReport {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	report := &ReferrerReport{
+		Username:   s.Username,
+		Repository: s.Repository,
+		Referrers:  []*Referrer{},
+	}
+	if s.Referrers != nil {
+		report.Referrers = s.Referrers.Top()
+	}
+	return report
+}
+
+func (r *ReferrerReport) String() string {
+	lines := make([]string, len(r.Referrers))
+	for i, referrer := range r.Referrers {
+		lines[i] = strings.Join([]string{
+			r.Username,
+			r.Repository,
+			referrer.Name,
+			fmt.Sprintf("%d", referrer.Hits),
+			fmt.Sprintf("%d", referrer.Error),
+		}, ",")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,71 @@
+package counter
+
+// GetRollup sums up the totals of the repository the given section belongs
+// to, including all of its pages, and of its owner. Sections which aren't
+// loaded are read from the store only once, hence roll-ups are served from
+// memory.
+func (c *Counter) GetRollup(section *Section) *Rollup {
+	rollup := &Rollup{}
+	username, repository, _ := splitKey(section.GetKey())
+	repositoryKey := SectionKey(username, repository, "")
+	for _, key := range c.Index.Get(username) {
+		total := c.repositoryTotal(key, rollup)
+		rollup.Owner += total
+		if key == repositoryKey {
+			rollup.Repository = total
+		}
+	}
+	return rollup
+}
+
+// repositoryTotal sums up the totals of the given repository and its pages
+// and updates the last change of the roll-up
+func (c *Counter) repositoryTotal(repositoryKey string, rollup *Rollup) int64 {
+	total := int64(0)
+	for _, key := range append([]string{repositoryKey}, c.Index.Get(repositoryKey)...) {
+		stored := c.total(key)
+		total += stored.total
+		if stored.updatedAt.After(rollup.UpdatedAt) {
+			rollup.UpdatedAt = stored.updatedAt
+		}
+	}
+	return total
+}
+
+// total returns the total of the given section without loading it. Totals
+// of sections which aren't loaded are read from the store only once.
+func (c *Counter) total(sectionKey string) *storedTotal {
+	sh := c.shard(sectionKey)
+	sh.mx.Lock()
+	section := sh.loaded(sectionKey)
+	stored, ok := sh.stored[sectionKey]
+	_, loading := sh.loading[sectionKey]
+	loads := sh.loads
+	sh.mx.Unlock()
+	if section != nil {
+		section.mx.RLock()
+		defer section.mx.RUnlock()
+		return &storedTotal{total: section.Total, updatedAt: section.UpdatedAt}
+	}
+	if ok {
+		return stored
+	}
+
+	stored = &storedTotal{}
+	record, err := c.Store.Load(sectionKey)
+	if err != nil && err != ErrNotStored {
+		return stored
+	}
+	if err == nil && record.Section != nil {
+		stored.total = record.Total
+		stored.updatedAt = record.UpdatedAt
+	}
+
+	// The stored section may have changed if it has been loaded meanwhile
+	sh.mx.Lock()
+	if !loading && sh.loads == loads && sh.loaded(sectionKey) == nil {
+		sh.stored[sectionKey] = stored
+	}
+	sh.mx.Unlock()
+	return stored
+}
@@ -0,0 +1,36 @@
+package counter
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestRollup(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	c := NewCounterWithStore(time.Hour, NewFileStore(dir))
+	c.GetPage("user", "repository", "").IncrementBy(1)
+	c.GetPage("user", "repository", "page").IncrementBy(2)
+	c.GetPage("user", "other", "").IncrementBy(4)
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	c = NewCounterWithStore(time.Hour, NewFileStore(dir))
+	section := c.GetPage("user", "repository", "")
+	assertRollup(t, c.GetRollup(section), 3, 7)
+
+	// Totals read from the store are replaced once the section is loaded
+	c.Increment(c.GetPage("user", "other", ""))
+	c.Increment(c.GetPage("user", "repository", "page"))
+	assertRollup(t, c.GetRollup(section), 4, 9)
+}
+
+func assertRollup(t *testing.T, rollup *Rollup, repository int64, owner int64) {
+	t.Helper()
+	if rollup.Repository != repository || rollup.Owner != owner {
+		t.Errorf("rolled up %d/%d hits, expected %d/%d", rollup.Repository, rollup.Owner, repository, owner)
+	}
+}
@@ -1,34 +1,44 @@
 package counter
 
 import (
-	"../filesystem"
 	"crypto/sha256"
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"os"
-	"path"
 	"strings"
 	"time"
 )
 
 func NewSection(username string, repository string) *Section {
+	return NewPage(username, repository, "")
+}
+
+// NewPage creates the section of a page below the given repository. The
+// section isn't persisted unless it's loaded by a counter.
+func NewPage(username string, repository string, page string) *Section {
 	c := &Section{
 		Username:   username,
 		Repository: repository,
+		Page:       page,
 		Total:      0,
 		CreatedAt:  time.Now(),
+		History:    NewHistory(),
 		Entries:    make(map[string]*Entry),
 	}
-	_ = c.Load("")
 	return c
 }
 
 func (s *Section) GetKey() string {
-	return s.Username + "/" + s.Repository
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	return s.key()
+}
+
+func (s *Section) key() string {
+	return SectionKey(s.Username, s.Repository, s.Page)
 }
 
 func (s *Section) String() string {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
 	dateFormat := "2006-01-02 15:04:05"
 	return strings.Join([]string{
 		s.Username,
@@ -36,81 +46,222 @@ func (s *Section) String() string {
 		fmt.Sprintf("%d", s.Total),
 		s.CreatedAt.Format(dateFormat),
 		s.UpdatedAt.Format(dateFormat),
+		fmt.Sprintf("%d", s.BotTotal),
 	}, ",")
 }
 
 func (s *Section) GetToken() string {
+	return token(s.GetKey())
+}
+
+func token(sectionKey string) string {
 	h := sha256.New()
-	h.Write([]byte(s.GetKey()))
+	h.Write([]byte(sectionKey))
 	return fmt.Sprintf("%x", h.Sum(nil))
 }
 
 func (s *Section) AddEntry(entry *Entry, duration time.Duration) bool {
+	added, _ := s.addEntry(entry, duration)
+	return added
+}
+
+// addEntry counts the given entry like AddEntry. The second value is false
+// if the section has been closed, the entry isn't counted then.
+func (s *Section) addEntry(entry *Entry, duration time.Duration) (bool, bool) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.closed {
+		return false, false
+	}
+	s.trackVisitor(entry.Hash, entry.Timestamp)
 	if _, ok := s.Entries[entry.Hash]; !ok {
 		s.Entries[entry.Hash] = entry
-		s.Increment()
-		return true
+		s.incrementBy(1)
+		return true, true
 	} else {
 		if time.Now().After(s.Entries[entry.Hash].Timestamp.Add(duration)) {
 			s.Entries[entry.Hash] = entry
-			s.Increment()
-			return true
+			s.incrementBy(1)
+			return true, true
+		}
+	}
+	return false, true
+}
+
+// expire removes all entries which are older than the given duration
+func (s *Section) expire(now time.Time, duration time.Duration) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	for hash, entry := range s.Entries {
+		if now.After(entry.Timestamp.Add(duration)) {
+			delete(s.Entries, hash)
 		}
 	}
-	return false
 }
 
 func (s *Section) Increment() {
-	s.Total += 1
-	s.UpdatedAt = time.Now()
+	s.IncrementBy(1)
 }
 
-func (s *Section) initFile(filename string) {
-	filesystem.CreateDirectory("data")
-	if len(filename) == 0 {
-		dir, _ := os.Getwd()
-		filename = path.Join(dir, "data", s.GetToken() + ".json")
-		_ = s.Load(filename)
-	}
-	s.File = filename
+func (s *Section) IncrementBy(n int64) {
+	s.increment(n)
 }
 
+// increment adds n hits unless the section has been closed
+func (s *Section) increment(n int64) bool {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.closed {
+		return false
+	}
+	s.incrementBy(n)
+	return true
+}
 
-func (s *Section) Load(filename string) error {
-	s.initFile(filename)
+func (s *Section) incrementBy(n int64) {
+	s.changed()
+	s.Total += n
+	s.UpdatedAt = time.Now()
+	s.History.Add(s.UpdatedAt, n)
+}
 
-	if _, err := os.Stat(filename); err == nil {
+// IncrementBot counts a hit caused by a bot. Bot hits are tracked separately
+// and don't affect the total or the history. It returns false if the section
+// has been closed, the hit isn't counted then.
+func (s *Section) IncrementBot() bool {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.closed {
+		return false
+	}
+	s.changed()
+	s.BotTotal += 1
+	return true
+}
 
-		content, err := ioutil.ReadFile(filename)
-		if err != nil {
-			return err
-		}
+func (s *Section) GetHistory(from time.Time, to time.Time, granularity string) *HistoryReport {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	return newHistoryReport(s.Username, s.Repository, s.Page, s.History, from, to, granularity)
+}
 
-		err = json.Unmarshal(content, s)
-		if err != nil {
-			return err
-		}
+// Load replaces the data of the section by the stored one. A section which
+// isn't stored yet is marked as changed, so it's saved by the next flush.
+func (s *Section) Load() error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.store == nil {
+		return nil
+	}
 
-	} else {
-		_ = s.Save()
+	record, err := s.store.Load(s.key())
+	if err == ErrNotStored {
+		s.changed()
+		return nil
 	}
+	if err != nil {
+		return err
+	}
+	s.fromRecord(record)
 	return nil
 }
 
+// Save stores the section. Hits are only blocked while the section is
+// copied, concurrent saves are stored one after another. Closed sections
+// can't be saved anymore, ErrSectionClosed is returned then.
 func (s *Section) Save() error {
-	if len(s.File) == 0 {
-		s.initFile("")
-	}
+	return s.save(false)
+}
 
-	file, err := json.MarshalIndent(s, "", "\t")
-	if err != nil {
-		return err
+// save stores the section, unless dirty is set and it hasn't changed since
+// the last save. That's checked again once the previous save is done, since
+// the section may be closed and loaded anew afterwards, whose saves must not
+// be overwritten. Saving a closed section fails unless dirty is set.
+func (s *Section) save(dirty bool) error {
+	s.saveMx.Lock()
+	defer s.saveMx.Unlock()
+
+	s.mx.RLock()
+	if s.closed && !dirty {
+		s.mx.RUnlock()
+		return ErrSectionClosed
+	}
+	if s.closed || s.store == nil || dirty && s.revision == s.saved {
+		s.mx.RUnlock()
+		return nil
 	}
+	sectionKey := s.key()
+	revision := s.revision
+	record := s.record()
+	s.mx.RUnlock()
 
-	err = ioutil.WriteFile(s.File, file, 0644)
-	if err != nil {
+	if err := s.store.Save(sectionKey, record); err != nil {
 		return err
 	}
 
+	s.mx.Lock()
+	s.saved = revision
+	s.mx.Unlock()
 	return nil
-}
\ No newline at end of file
+}
+
+// flush saves the section if it changed since it has been saved the last time
+func (s *Section) flush() error {
+	if !s.isDirty() {
+		return nil
+	}
+	return s.save(true)
+}
+
+// changed marks the section as dirty, the section has to be locked
+func (s *Section) changed() {
+	s.revision++
+}
+
+func (s *Section) isDirty() bool {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	return s.revision != s.saved
+}
+
+func (s *Section) isClosed() bool {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	return s.closed
+}
+
+// closeSaved closes the section unless it changed since it has been saved
+// or it's held by an operation. Saves in progress aren't awaited, they can
+// only store the same revision.
+func (s *Section) closeSaved() bool {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.revision != s.saved || s.pins > 0 {
+		return false
+	}
+	s.closed = true
+	return true
+}
+
+// close prevents the section from being saved again. Saves in progress are
+// awaited, so it can be deleted from the store afterwards.
+func (s *Section) close() {
+	s.saveMx.Lock()
+	defer s.saveMx.Unlock()
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.closed = true
+}
+
+// pin keeps the section from being closed by evictions until unpin is called
+func (s *Section) pin() {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.pins++
+}
+
+func (s *Section) unpin() {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.pins--
+}
@@ -0,0 +1,86 @@
+package counter
+
+import (
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingStore counts the saves of each section
+type countingStore struct {
+	Store
+
+	mx    sync.Mutex
+	saves map[string]int
+}
+
+func (s *countingStore) Save(sectionKey string, record *Record) error {
+	s.mx.Lock()
+	s.saves[sectionKey]++
+	s.mx.Unlock()
+	return s.Store.Save(sectionKey, record)
+}
+
+func (s *countingStore) count(sectionKey string) int {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	return s.saves[sectionKey]
+}
+
+func TestFlushDirty(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	store := &countingStore{Store: NewFileStore(dir), saves: make(map[string]int)}
+	c := NewCounterWithStore(time.Hour, store)
+	defer c.Close()
+
+	// New sections are saved by the next flush instead of right away
+	section := c.GetPage("user", "repository", "")
+	if n := store.count("user/repository"); n != 0 {
+		t.Errorf("new section saved %d times before it has been flushed", n)
+	}
+	c.Flush()
+	if n := store.count("user/repository"); n != 1 {
+		t.Errorf("new section saved %d times by the flush, expected once", n)
+	}
+
+	// Clean sections aren't saved again
+	c.Flush()
+	if err := section.flush(); err != nil {
+		t.Fatal(err)
+	}
+	if n := store.count("user/repository"); n != 1 {
+		t.Errorf("clean section saved %d times, expected once", n)
+	}
+
+	c.Increment(section)
+	c.Flush()
+	c.Flush()
+	if n := store.count("user/repository"); n != 2 {
+		t.Errorf("changed section saved %d times, expected twice", n)
+	}
+}
+
+func TestFlushStored(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	c := NewCounterWithStore(time.Hour, NewFileStore(dir))
+	c.IncrementBy(c.GetPage("user", "repository", ""), 2)
+	c.Flush()
+	if err := c.Store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Stored sections are loaded clean
+	store := &countingStore{Store: NewFileStore(dir), saves: make(map[string]int)}
+	c = NewCounterWithStore(time.Hour, store)
+	defer c.Close()
+	assertTotal(t, c.GetPage("user", "repository", ""), 2)
+	c.Flush()
+	if n := store.count("user/repository"); n != 0 {
+		t.Errorf("loaded section saved %d times, expected none", n)
+	}
+}
@@ -0,0 +1,122 @@
+package counter
+
+import (
+	"errors"
+	"os"
+	"path"
+)
+
+const (
+	StorageJSON = "json"
+	StorageBolt = "bolt"
+)
+
+var (
+	ErrNotStored      = errors.New("section not stored")
+	ErrUnknownStorage = errors.New("unknown storage backend, expected json or bolt")
+)
+
+// Store persists sections identified by their key. Implementations have to
+// be safe for concurrent use.
+type Store interface {
+	// Load returns the stored section or ErrNotStored if it doesn't exist
+	Load(sectionKey string) (*Record, error)
+	// Save replaces the stored section
+	Save(sectionKey string, record *Record) error
+	// Delete removes the stored section, deleting a missing one is no error
+	Delete(sectionKey string) error
+	// List returns the keys of all stored sections
+	List() ([]string, error)
+	// History returns the stored history of the section without decoding the
+	// rest of it, or ErrNotStored if it doesn't exist
+	History(sectionKey string) (*History, error)
+	// LoadIndex returns the stored index or ErrNotStored if it doesn't exist
+	LoadIndex() (map[string]map[string]bool, error)
+	// SaveIndex replaces the stored index
+	SaveIndex(children map[string]map[string]bool) error
+	Close() error
+}
+
+// indexRecord is the stored representation of the index
+type indexRecord struct {
+	Children map[string]map[string]bool `json:"children"`
+}
+
+// NewStore opens the given storage backend. The json backend stores one file
+// per section in the data directory, the bolt backend uses a single database
+// file.
+func NewStore(backend string, file string) (Store, error) {
+	switch backend {
+	case StorageJSON, "":
+		return NewFileStore(dataDir()), nil
+	case StorageBolt:
+		return NewBoltStore(file)
+	}
+	return nil, ErrUnknownStorage
+}
+
+func dataDir() string {
+	dir, _ := os.Getwd()
+	return path.Join(dir, "data")
+}
+
+// record returns a copy of the section including all of its breakdowns,
+// which can be stored without holding the lock
+func (s *Section) record() *Record {
+	record := &Record{
+		Section:   s.snapshot(),
+		Countries: copyHits(s.Countries),
+	}
+	if s.History != nil {
+		record.History = &History{
+			Hourly: make(map[int64]int64, len(s.History.Hourly)),
+			Daily:  make(map[int64]int64, len(s.History.Daily)),
+		}
+		record.History.Merge(s.History)
+	}
+	if s.Unique != nil {
+		record.Unique = s.Unique.copy()
+	}
+	if s.Referrers != nil {
+		record.Referrers = &TopK{Items: make(map[string]*TopKItem, len(s.Referrers.Items))}
+		for name, item := range s.Referrers.Items {
+			record.Referrers.Items[name] = &TopKItem{Hits: item.Hits, Error: item.Error}
+		}
+	}
+	if s.Clients != nil {
+		record.Clients = &Clients{
+			Browsers:         copyHits(s.Clients.Browsers),
+			OperatingSystems: copyHits(s.Clients.OperatingSystems),
+			Devices:          copyHits(s.Clients.Devices),
+		}
+	}
+	return record
+}
+
+// fromRecord applies the stored data to the section
+func (s *Section) fromRecord(record *Record) {
+	if record.Section != nil {
+		s.Total = record.Total
+		s.BotTotal = record.BotTotal
+		s.CreatedAt = record.CreatedAt
+		s.UpdatedAt = record.UpdatedAt
+	}
+	if record.History != nil {
+		s.History = record.History
+	}
+	s.Unique = record.Unique
+	s.Referrers = record.Referrers
+	s.Countries = record.Countries
+	s.Clients = record.Clients
+}
+
+func copyHits(hits map[string]int64) map[string]int64 {
+	if hits == nil {
+		return nil
+	}
+	c := make(map[string]int64, len(hits))
+	for name, n := range hits {
+		c[name] = n
+	}
+	return c
+}
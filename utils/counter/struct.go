@@ -1,28 +1,269 @@
 package counter
 
 import (
+	"container/list"
 	"encoding/xml"
+	"sync"
 	"time"
 )
 
+// Counter holds the loaded sections. The sections are spread across shards
+// by their key, so requests to different sections rarely contend for the
+// same lock. All methods are safe for concurrent use.
 type Counter struct {
-	Sections map[string]*Section `json:"sections"`
-	File     string              `json:"-"`
-	Duration time.Duration       `json:"-"`
+	// Number of flushes which have been started, accessed atomically and
+	// hence first to be 64 bit aligned on 32 bit platforms
+	flushes uint64
+
+	Index    *Index        `json:"-"`
+	Store    Store         `json:"-"`
+	Policy   *Policy       `json:"-"`
+	File     string        `json:"-"`
+	Duration time.Duration `json:"-"`
+	// Changed sections are saved within this interval
+	FlushInterval time.Duration `json:"-"`
+	// Maximum number of sections which are saved at the same time
+	FlushConcurrency int `json:"-"`
+
+	shards []*shard
+	// Serializes operations affecting several sections, like renames
+	ops *sync.Mutex
+	// Evictions since they have been reported the last time, per reason
+	evictions   map[string]int64
+	evictionsMx *sync.Mutex
+}
+
+// shard holds a part of the loaded sections
+type shard struct {
+	mx       *sync.Mutex
+	sections map[string]*Section
+	// Keys of the resident sections, the most recently used one first
+	recent   *list.List
+	elements map[string]*list.Element
+	// Sections unloaded by the LRU eviction, which may still receive hits of
+	// requests in progress. They are loaded again on their next request and
+	// dropped once they have been saved.
+	evicted map[string]*eviction
+	// Serializes shrinking the evicted sections
+	shrinkMx *sync.Mutex
+	// Sections which are being loaded from the store or blocked by an
+	// operation, the channel is closed once they are available again
+	loading map[string]chan bool
+	// Totals of sections which aren't loaded as they are stored, read by
+	// roll-ups. A section has to be loaded to change, hence an entry stays
+	// valid until its section is loaded again.
+	stored map[string]*storedTotal
+	// Number of sections which have been loaded
+	loads uint64
+}
+
+type storedTotal struct {
+	total     int64
+	updatedAt time.Time
+}
+
+type eviction struct {
+	section *Section
+	// Number of flushes started before the section has been evicted
+	flushes uint64
+}
+
+// Policy decides when sections are unloaded from memory or discarded
+type Policy struct {
+	// Sections with less hits are considered to be junk once they are older
+	// than JunkAge
+	JunkMinHits int64
+	JunkAge     time.Duration
+	// Delete the files of junk sections instead of only unloading them
+	JunkDeleteFiles bool
+	// Sections which haven't been requested within this duration are
+	// unloaded; 0 keeps them loaded
+	IdleTTL time.Duration
+	// Maximum number of loaded sections, the least recently used sections
+	// are unloaded first; 0 means unlimited
+	MaxSections int
+}
+
+// Index holds the keys of all known sections grouped by their parent: the
+// repositories of an owner and the pages of a repository
+type Index struct {
+	Children map[string]map[string]bool `json:"children"`
+
+	// Store the index is persisted in
+	store Store
+	mx    *sync.RWMutex
+	dirty bool
 }
 
 type Section struct {
+	// Last time the section has been requested in unix nanoseconds, accessed
+	// atomically and hence first to be 64 bit aligned on 32 bit platforms
+	accessed int64
+
 	XMLName    xml.Name          `xml:"Section" json:"-"`
 	Username   string            `json:"username"`
 	Repository string            `json:"repository"`
+	Page       string            `json:"page,omitempty" xml:",omitempty"`
 	Total      int64             `json:"total"`
+	BotTotal   int64             `json:"bot_total"`
 	CreatedAt  time.Time         `json:"created_at"`
 	UpdatedAt  time.Time         `json:"updated_at"`
+	History    *History          `xml:"-" json:"-"`
+	Unique     *Period           `xml:"-" json:"-"`
+	Referrers  *TopK             `xml:"-" json:"-"`
+	Countries  map[string]int64  `xml:"-" json:"-"`
+	Clients    *Clients          `xml:"-" json:"-"`
 	Entries    map[string]*Entry `xml:"-" json:"-"`
-	File       string            `xml:"-" json:"-"`
+
+	// Guards all fields above, the methods of a section are safe for
+	// concurrent use
+	mx sync.RWMutex
+	// Store the section is persisted in, sections without one aren't saved
+	store Store
+	// Serializes saving the section
+	saveMx sync.Mutex
+	// Incremented on every change, the section is dirty as long as it
+	// differs from the revision which has been saved the last time
+	revision uint64
+	saved    uint64
+	// Set once the section has been merged into another one or deleted, it
+	// won't be saved anymore
+	closed bool
+	// Number of operations holding the section, it isn't closed by
+	// evictions as long as it's held
+	pins int
+}
+
+// Record is the stored representation of a section
+type Record struct {
+	*Section
+	History   *History         `json:"history"`
+	Unique    *Period          `json:"unique"`
+	Referrers *TopK            `json:"referrers"`
+	Countries map[string]int64 `json:"countries"`
+	Clients   *Clients         `json:"clients"`
+}
+
+// Period counts hits within a period starting at Start
+type Period struct {
+	Start time.Time `json:"start"`
+	Hits  int64     `json:"hits"`
+	// Visitors seen during the period, if the hits are unique visitors
+	Visitors *HyperLogLog `json:"visitors,omitempty"`
+}
+
+// HyperLogLog estimates the number of distinct values using a fixed amount
+// of memory, one byte per register. The standard error is about 3%.
+type HyperLogLog struct {
+	Registers []uint8 `json:"registers"`
+
+	// Sum of 2^-register over all registers and the number of empty ones,
+	// valid if counted is set
+	sum     float64
+	zeros   int
+	counted bool
+}
+
+// Stats is a section extended by the value of a requested metric
+type Stats struct {
+	*Section
+	Metric string  `json:"metric,omitempty" xml:",omitempty"`
+	Value  int64   `json:"value"`
+	Rollup *Rollup `json:"rollup,omitempty" xml:",omitempty"`
+}
+
+// Rollup holds the totals of the repository including all of its pages and
+// of the owner including all of its repositories
+type Rollup struct {
+	Repository int64 `json:"repository"`
+	Owner      int64 `json:"owner"`
+	// Last time any of the summed up sections changed
+	UpdatedAt time.Time `json:"-" xml:"-"`
 }
 
 type Entry struct {
 	Hash      string
 	Timestamp time.Time
 }
+
+// History holds the number of hits per hour and per day. The buckets are
+// keyed by the unix timestamp of their start in UTC.
+type History struct {
+	Hourly map[int64]int64 `json:"hourly"`
+	Daily  map[int64]int64 `json:"daily"`
+}
+
+type HistoryReport struct {
+	XMLName     xml.Name  `xml:"History" json:"-"`
+	Username    string    `json:"username"`
+	Repository  string    `json:"repository"`
+	Page        string    `json:"page,omitempty" xml:",omitempty"`
+	Granularity string    `json:"granularity"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	Buckets     []*Bucket `xml:"Bucket" json:"buckets"`
+}
+
+type Bucket struct {
+	Time time.Time `json:"time"`
+	Hits int64     `json:"hits"`
+}
+
+// TopK keeps track of the most frequent names using the space saving
+// algorithm, which requires a fixed amount of memory. Counts of names
+// which replaced an evicted one may be overestimated by up to Error.
+type TopK struct {
+	Items map[string]*TopKItem `json:"items"`
+}
+
+type TopKItem struct {
+	Hits  int64 `json:"hits"`
+	Error int64 `json:"error"`
+}
+
+type ReferrerReport struct {
+	XMLName    xml.Name    `xml:"Referrers" json:"-"`
+	Username   string      `json:"username"`
+	Repository string      `json:"repository"`
+	Referrers  []*Referrer `xml:"Referrer" json:"referrers"`
+}
+
+type Referrer struct {
+	Name  string `json:"name"`
+	Hits  int64  `json:"hits"`
+	Error int64  `json:"error"`
+}
+
+type CountryReport struct {
+	XMLName    xml.Name   `xml:"Countries" json:"-"`
+	Username   string     `json:"username"`
+	Repository string     `json:"repository"`
+	Countries  []*Country `xml:"Country" json:"countries"`
+}
+
+type Country struct {
+	Code string `json:"code"`
+	Hits int64  `json:"hits"`
+}
+
+// Clients holds the number of hits per browser, operating system and device
+// class
+type Clients struct {
+	Browsers         map[string]int64 `json:"browsers"`
+	OperatingSystems map[string]int64 `json:"operating_systems"`
+	Devices          map[string]int64 `json:"devices"`
+}
+
+type ClientReport struct {
+	XMLName          xml.Name `xml:"Clients" json:"-"`
+	Username         string   `json:"username"`
+	Repository       string   `json:"repository"`
+	Browsers         []*Share `xml:"Browser" json:"browsers"`
+	OperatingSystems []*Share `xml:"OS" json:"operating_systems"`
+	Devices          []*Share `xml:"Device" json:"devices"`
+}
+
+type Share struct {
+	Name string `json:"name"`
+	Hits int64  `json:"hits"`
+}
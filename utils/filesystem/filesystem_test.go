@@ -0,0 +1,41 @@
+package filesystem
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filesystem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := path.Join(dir, "section.json")
+	for _, content := range []string{`{"total":1}`, `{}`} {
+		if err := WriteFileAtomic(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		written, err := ioutil.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(written) != content {
+			t.Errorf("wrote %q, expected %q", written, content)
+		}
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("left %d files, expected only the written one", len(files))
+	}
+	if mode := files[0].Mode().Perm(); mode != 0644 {
+		t.Errorf("wrote the file with mode %o, expected 644", mode)
+	}
+}
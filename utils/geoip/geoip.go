@@ -0,0 +1,62 @@
+package geoip
+
+import (
+	"github.com/oschwald/maxminddb-golang"
+	"net"
+	"strings"
+	"sync"
+)
+
+// Unknown is used for addresses which aren't part of the database
+const Unknown = "ZZ"
+
+func NewResolver(file string) (*Resolver, error) {
+	reader, err := maxminddb.Open(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Resolver{
+		File:   file,
+		mx:     &sync.RWMutex{},
+		reader: reader,
+	}, nil
+}
+
+// Country returns the ISO 3166-1 alpha-2 code of the country the given ip
+// address belongs to. The registered country is used if the actual country
+// is unknown and Unknown if neither of them is available.
+func (r *Resolver) Country(ip net.IP) string {
+	if ip == nil {
+		return Unknown
+	}
+
+	r.mx.RLock()
+	defer r.mx.RUnlock()
+	if r.reader == nil {
+		return Unknown
+	}
+
+	rec := &record{}
+	if err := r.reader.Lookup(ip, rec); err != nil {
+		return Unknown
+	}
+	if rec.Country.ISOCode != "" {
+		return strings.ToUpper(rec.Country.ISOCode)
+	}
+	if rec.RegisteredCountry.ISOCode != "" {
+		return strings.ToUpper(rec.RegisteredCountry.ISOCode)
+	}
+	return Unknown
+}
+
+func (r *Resolver) Close() error {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	if r.reader == nil {
+		return nil
+	}
+	err := r.reader.Close()
+	r.reader = nil
+	return err
+}
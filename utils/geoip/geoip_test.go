@@ -0,0 +1,34 @@
+package geoip
+
+import (
+	"net"
+	"testing"
+)
+
+func TestResolverCountry(t *testing.T) {
+	r, err := NewResolver("testdata/GeoLite2-Country-Test.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	tests := map[string]string{
+		"81.2.69.142":    "GB",
+		"2a02:8100::1":   "DE",
+		"198.51.100.1":   Unknown,
+		"not an ip":      Unknown,
+		"::ffff:1.1.1.1": Unknown,
+	}
+	for ip, country := range tests {
+		if c := r.Country(net.ParseIP(ip)); c != country {
+			t.Errorf("resolved %s to %s, expected %s", ip, c, country)
+		}
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if c := r.Country(net.ParseIP("81.2.69.142")); c != Unknown {
+		t.Errorf("resolved %s after closing the database", c)
+	}
+}
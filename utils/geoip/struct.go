@@ -0,0 +1,26 @@
+package geoip
+
+import (
+	"github.com/oschwald/maxminddb-golang"
+	"sync"
+)
+
+// Resolver looks up the country of an ip address in a local MaxMind format
+// database. No network requests are made.
+type Resolver struct {
+	File string
+
+	mx     *sync.RWMutex
+	reader *maxminddb.Reader
+}
+
+// record contains the fields of a GeoIP2 / GeoLite2 country or city record
+// required to determine the country
+type record struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	RegisteredCountry struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"registered_country"`
+}
@@ -0,0 +1,50 @@
+//go:build ignore
+// +build ignore
+
+// Generates the test database, run it within this directory:
+//
+//	go run generate.go
+package main
+
+import (
+	"github.com/maxmind/mmdbwriter"
+	"github.com/maxmind/mmdbwriter/mmdbtype"
+	"log"
+	"net"
+	"os"
+)
+
+func main() {
+	w, err := mmdbwriter.New(mmdbwriter.Options{
+		DatabaseType: "GeoLite2-Country",
+		RecordSize:   24,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	records := map[string]mmdbtype.Map{
+		// Country known
+		"81.2.69.0/24": {"country": mmdbtype.Map{"iso_code": mmdbtype.String("GB")}},
+		// Only the registered country known, lower case
+		"2a02:8100::/32": {"registered_country": mmdbtype.Map{"iso_code": mmdbtype.String("de")}},
+	}
+	for cidr, record := range records {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := w.Insert(network, record); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	f, err := os.Create("GeoLite2-Country-Test.mmdb")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := w.WriteTo(f); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+var Default = NewRegistry()
+
+func NewRegistry() *Registry {
+	return &Registry{
+		mx:       &sync.RWMutex{},
+		counters: make(map[string]*int64),
+	}
+}
+
+// Add increases the counter with the given name by delta. Names follow the
+// prometheus notation and may contain labels, e.g. `hits_total{type="bot"}`.
+func (r *Registry) Add(name string, delta int64) {
+	r.mx.RLock()
+	counter, ok := r.counters[name]
+	r.mx.RUnlock()
+
+	if !ok {
+		r.mx.Lock()
+		if counter, ok = r.counters[name]; !ok {
+			counter = new(int64)
+			r.counters[name] = counter
+		}
+		r.mx.Unlock()
+	}
+	atomic.AddInt64(counter, delta)
+}
+
+func (r *Registry) Get(name string) int64 {
+	r.mx.RLock()
+	defer r.mx.RUnlock()
+	if counter, ok := r.counters[name]; ok {
+		return atomic.LoadInt64(counter)
+	}
+	return 0
+}
+
+// WriteTo writes all counters sorted by name in the prometheus text format
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mx.RLock()
+	names := make([]string, 0, len(r.counters))
+	for name := range r.counters {
+		names = append(names, name)
+	}
+	r.mx.RUnlock()
+	sort.Strings(names)
+
+	total := int64(0)
+	for _, name := range names {
+		n, err := fmt.Fprintf(w, "gohits_%s %d\n", name, r.Get(name))
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+func Add(name string, delta int64) {
+	Default.Add(name, delta)
+}
+
+func Get(name string) int64 {
+	return Default.Get(name)
+}
@@ -0,0 +1,8 @@
+package metrics
+
+import "sync"
+
+type Registry struct {
+	mx       *sync.RWMutex
+	counters map[string]*int64
+}
@@ -0,0 +1,128 @@
+package number
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	// 1234567 -> 1.2M
+	ModeShort = "short"
+	// 1234567 -> 1234567
+	ModeFull = "full"
+	// 1234567 -> 1,234,567
+	ModeGrouped = "grouped"
+
+	DefaultMode      = ModeShort
+	DefaultPrecision = 1
+	DefaultLocale    = "en"
+
+	MaxPrecision = 3
+)
+
+var modes = map[string]bool{
+	ModeShort:   true,
+	ModeFull:    true,
+	ModeGrouped: true,
+}
+
+var Locales = map[string]*Locale{
+	"en": {Thousands: ",", Decimal: "."},
+	"de": {Thousands: ".", Decimal: ","},
+	"es": {Thousands: ".", Decimal: ","},
+	"it": {Thousands: ".", Decimal: ","},
+	"nl": {Thousands: ".", Decimal: ","},
+	"fr": {Thousands: " ", Decimal: ","},
+	"ch": {Thousands: "'", Decimal: "."},
+}
+
+var units = []string{"", "k", "M", "B", "T"}
+
+func NewFormatter(mode string, precision int, locale string) *Formatter {
+	f := &Formatter{
+		Mode:      DefaultMode,
+		Precision: DefaultPrecision,
+		Locale:    DefaultLocale,
+	}
+	return f.With(mode, precision, locale)
+}
+
+// With returns a copy of the formatter using the given options. Invalid
+// options are ignored and the current value is kept instead.
+func (f *Formatter) With(mode string, precision int, locale string) *Formatter {
+	c := *f
+	if mode = strings.ToLower(mode); modes[mode] {
+		c.Mode = mode
+	}
+	if precision >= 0 && precision <= MaxPrecision {
+		c.Precision = precision
+	}
+	if locale = strings.ToLower(locale); Locales[locale] != nil {
+		c.Locale = locale
+	}
+	return &c
+}
+
+func (f *Formatter) Format(n int64) string {
+	l := Locales[f.Locale]
+	if l == nil {
+		l = Locales[DefaultLocale]
+	}
+
+	switch f.Mode {
+	case ModeFull:
+		return strconv.FormatInt(n, 10)
+	case ModeGrouped:
+		return group(n, l.Thousands)
+	}
+	return f.short(n, l)
+}
+
+// short abbreviates the given number using the units k, M, B and T. Trailing
+// zeros of the fraction are removed (1.0k -> 1k).
+func (f *Formatter) short(n int64, l *Locale) string {
+	value := float64(n)
+	sign := ""
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+
+	unit := 0
+	for value >= 1000 && unit < len(units)-1 {
+		value /= 1000
+		unit++
+	}
+
+	// Rounding might push the value into the next unit (999.95k -> 1M)
+	p := math.Pow(10, float64(f.Precision))
+	value = math.Round(value*p) / p
+	if value >= 1000 && unit < len(units)-1 {
+		value /= 1000
+		unit++
+	}
+
+	s := strconv.FormatFloat(value, 'f', f.Precision, 64)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return sign + strings.Replace(s, ".", l.Decimal, 1) + units[unit]
+}
+
+func group(n int64, separator string) string {
+	s := strconv.FormatInt(n, 10)
+	sign := ""
+	if n < 0 {
+		sign, s = "-", s[1:]
+	}
+
+	var parts []string
+	for len(s) > 3 {
+		parts = append([]string{s[len(s)-3:]}, parts...)
+		s = s[:len(s)-3]
+	}
+	parts = append([]string{s}, parts...)
+
+	return sign + strings.Join(parts, separator)
+}
@@ -0,0 +1,74 @@
+package number
+
+import (
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		mode      string
+		precision int
+		locale    string
+		n         int64
+		expected  string
+	}{
+		{ModeShort, 1, "en", 0, "0"},
+		{ModeShort, 1, "en", 999, "999"},
+		{ModeShort, 1, "en", 1000, "1k"},
+		{ModeShort, 1, "en", 1234, "1.2k"},
+		{ModeShort, 1, "en", 1234567, "1.2M"},
+		{ModeShort, 1, "en", 999950, "1M"},
+		{ModeShort, 2, "en", 1234567890, "1.23B"},
+		{ModeShort, 0, "en", 1500, "2k"},
+		{ModeShort, 1, "en", -1234, "-1.2k"},
+		{ModeShort, 1, "en", 1234567890123456, "1234.6T"},
+		{ModeFull, 1, "en", 1234567, "1234567"},
+		{ModeGrouped, 1, "en", 999, "999"},
+		{ModeGrouped, 1, "en", -1234567, "-1,234,567"},
+	}
+	for _, test := range tests {
+		f := NewFormatter(test.mode, test.precision, test.locale)
+		if s := f.Format(test.n); s != test.expected {
+			t.Errorf("formatted %d (%s, %d) as %q, expected %q", test.n, test.mode, test.precision, s, test.expected)
+		}
+	}
+}
+
+func TestFormatLocales(t *testing.T) {
+	tests := map[string][2]string{
+		"en": {"1,234,567", "1.2M"},
+		"de": {"1.234.567", "1,2M"},
+		"es": {"1.234.567", "1,2M"},
+		"it": {"1.234.567", "1,2M"},
+		"nl": {"1.234.567", "1,2M"},
+		"fr": {"1\u202f234\u202f567", "1,2M"},
+		"ch": {"1'234'567", "1.2M"},
+	}
+	if len(tests) != len(Locales) {
+		t.Errorf("tested %d locales, expected all %d", len(tests), len(Locales))
+	}
+	for locale, expected := range tests {
+		if s := NewFormatter(ModeGrouped, 1, locale).Format(1234567); s != expected[0] {
+			t.Errorf("grouped 1234567 in %s as %q, expected %q", locale, s, expected[0])
+		}
+		if s := NewFormatter(ModeShort, 1, locale).Format(1234567); s != expected[1] {
+			t.Errorf("abbreviated 1234567 in %s as %q, expected %q", locale, s, expected[1])
+		}
+	}
+}
+
+func TestWith(t *testing.T) {
+	f := NewFormatter(ModeGrouped, 2, "DE")
+	if f.Mode != ModeGrouped || f.Precision != 2 || f.Locale != "de" {
+		t.Errorf("created %+v", f)
+	}
+
+	// Invalid options keep the current value
+	c := f.With("unknown", MaxPrecision+1, "xx")
+	if *c != *f {
+		t.Errorf("applied invalid options: %+v", c)
+	}
+	if c = f.With(ModeFull, 0, "fr"); c.Mode != ModeFull || c.Precision != 0 || c.Locale != "fr" || f.Mode != ModeGrouped {
+		t.Errorf("applied %+v to a copy of %+v", c, f)
+	}
+}
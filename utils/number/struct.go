@@ -0,0 +1,12 @@
+package number
+
+type Formatter struct {
+	Mode      string
+	Precision int
+	Locale    string
+}
+
+type Locale struct {
+	Thousands string
+	Decimal   string
+}
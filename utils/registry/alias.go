@@ -0,0 +1,165 @@
+package registry
+
+import (
+	"../filesystem"
+	"../log"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Maximum number of aliases followed to resolve a section
+const maxAliasDepth = 8
+
+var ErrAliasLoop = errors.New("alias would create a loop")
+
+func NewAliases(file string) *Aliases {
+	a := &Aliases{
+		File:    file,
+		mx:      &sync.RWMutex{},
+		aliases: make(map[string]string),
+	}
+	if err := a.Load(); err != nil && !os.IsNotExist(err) {
+		log.Error(err)
+	}
+	return a
+}
+
+// Resolve returns the section the given section key points to, following
+// chained aliases. Keys without an alias are returned as they are.
+func (a *Aliases) Resolve(sectionKey string) string {
+	a.mx.RLock()
+	defer a.mx.RUnlock()
+	for i := 0; i < maxAliasDepth; i++ {
+		target, ok := a.aliases[sectionKey]
+		if !ok {
+			break
+		}
+		sectionKey = target
+	}
+	return sectionKey
+}
+
+// Set redirects the section from to the section to
+func (a *Aliases) Set(from string, to string) error {
+	for _, key := range []string{from, to} {
+		if !validKey.MatchString(key) || !strings.Contains(key, "/") {
+			return ErrInvalidKey
+		}
+	}
+
+	a.mx.Lock()
+	defer a.mx.Unlock()
+	key := to
+	for i := 0; i < maxAliasDepth; i++ {
+		if key == from {
+			return ErrAliasLoop
+		}
+		next, ok := a.aliases[key]
+		if !ok {
+			break
+		}
+		key = next
+	}
+	a.aliases[from] = to
+	return nil
+}
+
+// Remove removes the alias of the given section and reports whether it
+// existed
+func (a *Aliases) Remove(from string) bool {
+	a.mx.Lock()
+	defer a.mx.Unlock()
+	if _, ok := a.aliases[from]; !ok {
+		return false
+	}
+	delete(a.aliases, from)
+	return true
+}
+
+func (a *Aliases) Entries() *AliasEntries {
+	a.mx.RLock()
+	defer a.mx.RUnlock()
+
+	entries := &AliasEntries{Aliases: make([]*Alias, 0, len(a.aliases))}
+	for from, to := range a.aliases {
+		entries.Aliases = append(entries.Aliases, &Alias{From: from, To: to})
+	}
+	sort.Slice(entries.Aliases, func(i, j int) bool {
+		return entries.Aliases[i].From < entries.Aliases[j].From
+	})
+	return entries
+}
+
+func (e *AliasEntries) String() string {
+	lines := make([]string, len(e.Aliases))
+	for i, alias := range e.Aliases {
+		lines[i] = fmt.Sprintf("%s,%s", alias.From, alias.To)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (a *Aliases) Load() error {
+	content, err := ioutil.ReadFile(a.File)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(a.File)
+	if err != nil {
+		return err
+	}
+
+	aliases := make(map[string]string)
+	if err := json.Unmarshal(content, &aliases); err != nil {
+		return err
+	}
+
+	a.mx.Lock()
+	a.aliases = aliases
+	a.modTime = info.ModTime()
+	a.mx.Unlock()
+	return nil
+}
+
+func (a *Aliases) Save() error {
+	a.mx.RLock()
+	content, err := json.MarshalIndent(a.aliases, "", "\t")
+	a.mx.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	_, _ = filesystem.MakeDir(a.File)
+	if err := ioutil.WriteFile(a.File, content, 0644); err != nil {
+		return err
+	}
+
+	if info, err := os.Stat(a.File); err == nil {
+		a.mx.Lock()
+		a.modTime = info.ModTime()
+		a.mx.Unlock()
+	}
+	return nil
+}
+
+// Watch reloads the aliases whenever the alias file is changed by another
+// process, e.g. the command line
+func (a *Aliases) Watch(interval time.Duration) {
+	watch(a.File, interval, func() time.Time {
+		a.mx.RLock()
+		defer a.mx.RUnlock()
+		return a.modTime
+	}, func() {
+		if err := a.Load(); err != nil {
+			log.Error(err)
+			return
+		}
+		log.Info("Aliases reloaded")
+	})
+}
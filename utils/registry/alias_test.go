@@ -0,0 +1,53 @@
+package registry
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestAliases(t *testing.T) {
+	dir, err := ioutil.TempDir("", "registry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := path.Join(dir, "aliases.json")
+	a := NewAliases(file)
+	for _, alias := range [][2]string{{"user/old", "user/renamed"}, {"user/renamed", "user/new"}} {
+		if err := a.Set(alias[0], alias[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.Set("user/new", "user/old"); err != ErrAliasLoop {
+		t.Errorf("created a loop: %v", err)
+	}
+	if err := a.Set("user", "user/new"); err != ErrInvalidKey {
+		t.Errorf("created an alias of an owner: %v", err)
+	}
+	if err := a.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	a = NewAliases(file)
+	tests := map[string]string{
+		"user/old":     "user/new",
+		"user/renamed": "user/new",
+		"user/new":     "user/new",
+		"user/other":   "user/other",
+	}
+	for sectionKey, expected := range tests {
+		if resolved := a.Resolve(sectionKey); resolved != expected {
+			t.Errorf("resolved %s to %s, expected %s", sectionKey, resolved, expected)
+		}
+	}
+
+	if !a.Remove("user/renamed") || a.Remove("user/renamed") {
+		t.Error("removed user/renamed not exactly once")
+	}
+	if resolved := a.Resolve("user/old"); resolved != "user/renamed" {
+		t.Errorf("resolved user/old to %s, expected user/renamed", resolved)
+	}
+}
@@ -0,0 +1,184 @@
+package registry
+
+import (
+	"../filesystem"
+	"../log"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var validKey = regexp.MustCompile(`^[a-zA-Z0-9\-_.]+(/[a-zA-Z0-9\-_.]+)?$`)
+
+var ErrInvalidKey = errors.New("invalid key, expected username or username/repository")
+
+func NewRegistry(file string) *Registry {
+	r := &Registry{
+		File:     file,
+		mx:       &sync.RWMutex{},
+		sections: make(map[string]bool),
+		owners:   make(map[string]bool),
+	}
+	if err := r.Load(); err != nil && !os.IsNotExist(err) {
+		log.Error(err)
+	}
+	return r
+}
+
+// IsRegistered reports whether the given section or its owner is registered
+func (r *Registry) IsRegistered(username string, repository string) bool {
+	r.mx.RLock()
+	defer r.mx.RUnlock()
+	return r.owners[username] || r.sections[username+"/"+repository]
+}
+
+// Register registers the given key. Keys containing a slash refer to a
+// section (username/repository), all others to an owner (username) whose
+// sections are registered implicitly.
+func (r *Registry) Register(key string) error {
+	if !validKey.MatchString(key) {
+		return ErrInvalidKey
+	}
+
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	if strings.Contains(key, "/") {
+		r.sections[key] = true
+	} else {
+		r.owners[key] = true
+	}
+	return nil
+}
+
+// Unregister removes the given key and reports whether it was registered
+func (r *Registry) Unregister(key string) bool {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	keys := r.owners
+	if strings.Contains(key, "/") {
+		keys = r.sections
+	}
+	if _, ok := keys[key]; !ok {
+		return false
+	}
+	delete(keys, key)
+	return true
+}
+
+func (r *Registry) Entries() *Entries {
+	r.mx.RLock()
+	defer r.mx.RUnlock()
+	return &Entries{
+		Sections: sortKeys(r.sections),
+		Owners:   sortKeys(r.owners),
+	}
+}
+
+func (e *Entries) String() string {
+	return strings.Join(append(append([]string{}, e.Owners...), e.Sections...), "\n")
+}
+
+func (r *Registry) Load() error {
+	content, err := ioutil.ReadFile(r.File)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(r.File)
+	if err != nil {
+		return err
+	}
+
+	entries := &Entries{}
+	if err := json.Unmarshal(content, entries); err != nil {
+		return err
+	}
+
+	sections := make(map[string]bool)
+	owners := make(map[string]bool)
+	for _, key := range entries.Sections {
+		sections[key] = true
+	}
+	for _, key := range entries.Owners {
+		owners[key] = true
+	}
+
+	r.mx.Lock()
+	r.sections = sections
+	r.owners = owners
+	r.modTime = info.ModTime()
+	r.mx.Unlock()
+	return nil
+}
+
+func (r *Registry) Save() error {
+	content, err := json.MarshalIndent(r.Entries(), "", "\t")
+	if err != nil {
+		return err
+	}
+
+	_, _ = filesystem.MakeDir(r.File)
+	if err := ioutil.WriteFile(r.File, content, 0644); err != nil {
+		return err
+	}
+
+	if info, err := os.Stat(r.File); err == nil {
+		r.mx.Lock()
+		r.modTime = info.ModTime()
+		r.mx.Unlock()
+	}
+	return nil
+}
+
+// Watch reloads the registry whenever the registry file is changed by
+// another process, e.g. the command line
+func (r *Registry) Watch(interval time.Duration) {
+	watch(r.File, interval, func() time.Time {
+		r.mx.RLock()
+		defer r.mx.RUnlock()
+		return r.modTime
+	}, func() {
+		if err := r.Load(); err != nil {
+			log.Error(err)
+			return
+		}
+		log.Info("Registry reloaded")
+	})
+}
+
+// watch calls reload whenever the modification time of the given file
+// differs from the one returned by loaded
+func watch(file string, interval time.Duration, loaded func() time.Time, reload func()) {
+	t := time.NewTicker(interval)
+	defer func() {
+		t.Stop()
+	}()
+
+	for {
+		select {
+		case <-t.C:
+			info, err := os.Stat(file)
+			if err != nil {
+				continue
+			}
+			if !info.ModTime().Equal(loaded()) {
+				reload()
+			}
+		}
+	}
+}
+
+func sortKeys(keys map[string]bool) []string {
+	result := make([]string, 0, len(keys))
+	for key := range keys {
+		result = append(result, key)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,45 @@
+package registry
+
+import (
+	"encoding/xml"
+	"sync"
+	"time"
+)
+
+// Registry holds the sections and owners allowed to be counted if the
+// registration mode is enabled
+type Registry struct {
+	File string
+
+	mx       *sync.RWMutex
+	sections map[string]bool
+	owners   map[string]bool
+	modTime  time.Time
+}
+
+// Entries is the representation of a registry on disk and in the admin api
+type Entries struct {
+	XMLName  xml.Name `xml:"Registry" json:"-"`
+	Sections []string `xml:"Section" json:"sections"`
+	Owners   []string `xml:"Owner" json:"owners"`
+}
+
+// Aliases redirect sections to another section, e.g. after a repository
+// has been renamed
+type Aliases struct {
+	File string
+
+	mx      *sync.RWMutex
+	aliases map[string]string
+	modTime time.Time
+}
+
+type AliasEntries struct {
+	XMLName xml.Name `xml:"Aliases" json:"-"`
+	Aliases []*Alias `xml:"Alias" json:"aliases"`
+}
+
+type Alias struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
@@ -0,0 +1,128 @@
+package useragent
+
+import (
+	"../log"
+	"bufio"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Built-in bot patterns. Image proxies like GitHub's camo aren't listed on
+// purpose, since they fetch badges on behalf of real visitors. Names merely
+// ending with bot aren't matched, since they include devices like the Cubot
+// phones; most bots name themselves with a version or link to their docs.
+var DefaultBotPatterns = []string{
+	`\bbot\b`, `bot/`, `bot-`, `\+https?://`, `crawl`, `spider`, `slurp`, `archiver`,
+	`facebookexternalhit`, `facebookcatalog`, `embedly`, `skypeuripreview`, `bingpreview`,
+	`whatsapp`, `telegram`, `vkshare`, `pinterest`, `mastodon`, `quora link preview`,
+	`semrush`, `ahrefs`, `mj12`, `yandex`, `baidu`, `sogou`, `exabot`, `petalbot`, `bytespider`,
+	`curl/`, `wget/`, `python-requests`, `python-urllib`, `aiohttp`, `go-http-client`, `java/`,
+	`okhttp`, `libwww-perl`, `httpclient`, `axios/`, `node-fetch`,
+	`headlesschrome`, `phantomjs`, `lighthouse`, `pingdom`, `uptimerobot`, `statuscake`,
+	`feedfetcher`, `mediapartners`, `w3c_validator`,
+}
+
+// NewBotClassifier loads the patterns of the given file in addition to the
+// built-in ones. Requests without a user agent are treated as bots if
+// emptyUserAgent is set.
+func NewBotClassifier(file string, emptyUserAgent bool) *BotClassifier {
+	c := &BotClassifier{
+		File:           file,
+		EmptyUserAgent: emptyUserAgent,
+		mx:             &sync.RWMutex{},
+	}
+	c.Reload()
+	return c
+}
+
+// IsBot reports whether the given user agent belongs to a bot. Requests
+// without any user agent are treated as bots if EmptyUserAgent is set.
+func (c *BotClassifier) IsBot(userAgent string) bool {
+	if strings.TrimSpace(userAgent) == "" {
+		return c.EmptyUserAgent
+	}
+
+	c.mx.RLock()
+	defer c.mx.RUnlock()
+	return c.pattern.MatchString(userAgent)
+}
+
+// Reload combines the built-in patterns with the patterns of the pattern
+// file. The file contains one case insensitive regular expression per line,
+// empty lines and lines starting with # are ignored. Invalid patterns are
+// logged and skipped.
+func (c *BotClassifier) Reload() {
+	patterns := append([]string{}, DefaultBotPatterns...)
+	modTime := time.Time{}
+
+	if c.File != "" {
+		if info, err := os.Stat(c.File); err == nil {
+			modTime = info.ModTime()
+			patterns = append(patterns, readPatterns(c.File)...)
+		}
+	}
+
+	pattern := regexp.MustCompile("(?i)" + strings.Join(patterns, "|"))
+
+	c.mx.Lock()
+	c.pattern = pattern
+	c.modTime = modTime
+	c.mx.Unlock()
+}
+
+// Watch reloads the patterns whenever the pattern file changes
+func (c *BotClassifier) Watch(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer func() {
+		t.Stop()
+	}()
+
+	for {
+		select {
+		case <-t.C:
+			modTime := time.Time{}
+			if info, err := os.Stat(c.File); err == nil {
+				modTime = info.ModTime()
+			}
+
+			c.mx.RLock()
+			changed := !modTime.Equal(c.modTime)
+			c.mx.RUnlock()
+
+			if changed {
+				c.Reload()
+				log.Info("Bot patterns reloaded")
+			}
+		}
+	}
+}
+
+func readPatterns(filename string) []string {
+	file, err := os.Open(filename)
+	if err != nil {
+		log.Error(err)
+		return nil
+	}
+	defer file.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if _, err := regexp.Compile(line); err != nil {
+			log.Error("invalid bot pattern: ", line)
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	if err := scanner.Err(); err != nil {
+		log.Error(err)
+	}
+	return patterns
+}
@@ -0,0 +1,86 @@
+package useragent
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestIsBot(t *testing.T) {
+	c := NewBotClassifier("", true)
+	tests := []struct {
+		userAgent string
+		bot       bool
+	}{
+		// Browsers
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Safari/537.36", false},
+		{"Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:119.0) Gecko/20100101 Firefox/119.0", false},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Safari/605.1.15", false},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1", false},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Safari/537.36 Edg/118.0.2088.76", false},
+		{"Mozilla/5.0 (Linux; Android 13; SAMSUNG SM-S911B) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/23.0 Chrome/115.0.0.0 Mobile Safari/537.36", false},
+		{"Mozilla/5.0 (Linux; Android 9; CUBOT X19) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Mobile Safari/537.36", false},
+		{"Mozilla/5.0 (Linux; Android 10; KINGKONG 5 Pro Build/QP1A.190711.020; wv) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/118.0.0.0 Mobile Safari/537.36", false},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Safari/537.36 OPR/104.0.0.0", false},
+		// Image proxies fetch badges on behalf of visitors
+		{"github-camo (876de43e)", false},
+		// Bots
+		{"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", true},
+		{"Mozilla/5.0 (compatible; bingbot/2.0; +http://www.bing.com/bingbot.htm)", true},
+		{"Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)", true},
+		{"Slackbot 1.0 (+https://api.slack.com/robots)", true},
+		{"Twitterbot/1.0", true},
+		{"Mozilla/5.0 (compatible; archive.org_bot +http://archive.org/details/archive.org_bot)", true},
+		{"facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)", true},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) HeadlessChrome/118.0.0.0 Safari/537.36", true},
+		{"TelegramBot (like TwitterBot)", true},
+		{"curl/8.4.0", true},
+		{"python-requests/2.31.0", true},
+		{"Go-http-client/1.1", true},
+		{"", true},
+		{"  ", true},
+	}
+	for _, test := range tests {
+		if bot := c.IsBot(test.userAgent); bot != test.bot {
+			t.Errorf("classified %q as bot: %t, expected %t", test.userAgent, bot, test.bot)
+		}
+	}
+
+	if NewBotClassifier("", false).IsBot("") {
+		t.Error("classified an empty user agent as bot")
+	}
+}
+
+func TestReloadBotPatterns(t *testing.T) {
+	dir, err := ioutil.TempDir("", "useragent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := path.Join(dir, "bots.txt")
+	write := func(content string) {
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("# uptime checks\nMy-Uptime-Checker\n\n(invalid\n")
+
+	c := NewBotClassifier(file, true)
+	if !c.IsBot("my-uptime-checker 1.0") {
+		t.Error("pattern of the file hasn't been loaded")
+	}
+	if !c.IsBot("curl/8.4.0") {
+		t.Error("built-in patterns have been replaced")
+	}
+
+	write("^internal-preview/\\d+\n")
+	c.Reload()
+	if c.IsBot("my-uptime-checker 1.0") {
+		t.Error("removed pattern is still loaded")
+	}
+	if !c.IsBot("internal-preview/2") || c.IsBot("Mozilla/5.0 internal-preview/2") {
+		t.Error("changed pattern hasn't been loaded")
+	}
+}
@@ -0,0 +1,143 @@
+package useragent
+
+import (
+	"../log"
+	"bufio"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	FieldBrowser = "browser"
+	FieldOS      = "os"
+	FieldDevice  = "device"
+
+	// Other is used if none of the rules match
+	Other = "Other"
+)
+
+func NewParser(file string) *Parser {
+	p := &Parser{
+		File:  file,
+		mx:    &sync.RWMutex{},
+		rules: make(map[string][]*Rule),
+	}
+	p.Reload()
+	return p
+}
+
+// Parse returns the browser, operating system and device class of the given
+// user agent. The first matching rule of each field wins.
+func (p *Parser) Parse(userAgent string) *Client {
+	p.mx.RLock()
+	defer p.mx.RUnlock()
+
+	return &Client{
+		Browser: p.match(FieldBrowser, userAgent),
+		OS:      p.match(FieldOS, userAgent),
+		Device:  p.match(FieldDevice, userAgent),
+	}
+}
+
+func (p *Parser) match(field string, userAgent string) string {
+	for _, rule := range p.rules[field] {
+		if rule.Pattern.MatchString(userAgent) {
+			return rule.Name
+		}
+	}
+	return Other
+}
+
+// Reload reads the rules file. The file is divided into a [browser], [os]
+// and [device] section, each containing one "Name = pattern" rule per line.
+// Patterns are case insensitive regular expressions. Empty lines and lines
+// starting with # are ignored, invalid rules are logged and skipped.
+func (p *Parser) Reload() {
+	rules := make(map[string][]*Rule)
+	modTime := time.Time{}
+
+	if info, err := os.Stat(p.File); err == nil {
+		modTime = info.ModTime()
+		rules = readRules(p.File)
+	} else {
+		log.Error(err)
+	}
+
+	p.mx.Lock()
+	p.rules = rules
+	p.modTime = modTime
+	p.mx.Unlock()
+}
+
+// Watch reloads the rules whenever the rules file changes
+func (p *Parser) Watch(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer func() {
+		t.Stop()
+	}()
+
+	for {
+		select {
+		case <-t.C:
+			modTime := time.Time{}
+			if info, err := os.Stat(p.File); err == nil {
+				modTime = info.ModTime()
+			}
+
+			p.mx.RLock()
+			changed := !modTime.Equal(p.modTime)
+			p.mx.RUnlock()
+
+			if changed {
+				p.Reload()
+				log.Info("User agent rules reloaded")
+			}
+		}
+	}
+}
+
+func readRules(filename string) map[string][]*Rule {
+	rules := make(map[string][]*Rule)
+
+	file, err := os.Open(filename)
+	if err != nil {
+		log.Error(err)
+		return rules
+	}
+	defer file.Close()
+
+	field := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			field = strings.ToLower(strings.TrimSpace(line[1 : len(line)-1]))
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if field == "" || len(parts) != 2 {
+			log.Error("invalid user agent rule: ", line)
+			continue
+		}
+		pattern, err := regexp.Compile("(?i)" + strings.TrimSpace(parts[1]))
+		if err != nil {
+			log.Error("invalid user agent rule: ", line)
+			continue
+		}
+		rules[field] = append(rules[field], &Rule{
+			Name:    strings.TrimSpace(parts[0]),
+			Pattern: pattern,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		log.Error(err)
+	}
+	return rules
+}
@@ -0,0 +1,97 @@
+package useragent
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestReadRules(t *testing.T) {
+	dir, err := ioutil.TempDir("", "useragent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := path.Join(dir, "useragents.txt")
+	content := `# comment
+Ignored = outside of a section
+
+[Browser]
+Edge = edg/
+Chrome = chrome/
+Invalid = (chrome
+Missing pattern
+
+[os]
+Android = android
+Linux = linux
+`
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rules := readRules(file)
+	expected := map[string][]string{
+		FieldBrowser: {"Edge", "Chrome"},
+		FieldOS:      {"Android", "Linux"},
+	}
+	if len(rules) != len(expected) {
+		t.Errorf("read %d sections, expected %d", len(rules), len(expected))
+	}
+	for field, names := range expected {
+		if len(rules[field]) != len(names) {
+			t.Errorf("read %d %s rules, expected %d", len(rules[field]), field, len(names))
+			continue
+		}
+		for i, name := range names {
+			if rules[field][i].Name != name {
+				t.Errorf("read %s rule %d as %s, expected %s", field, i, rules[field][i].Name, name)
+			}
+		}
+	}
+
+	// The first matching rule wins, patterns are case insensitive
+	p := NewParser(file)
+	client := p.Parse("Mozilla/5.0 (Linux; Android 13) Chrome/118.0 Mobile Safari/537.36 EdG/118.0")
+	if client.Browser != "Edge" || client.OS != "Android" || client.Device != Other {
+		t.Errorf("parsed %+v, expected Edge on Android and no device", client)
+	}
+}
+
+// TestParse parses common user agents with the shipped rules
+func TestParse(t *testing.T) {
+	p := NewParser(path.Join("..", "..", "conf", "useragents.txt"))
+	tests := []struct {
+		userAgent string
+		client    Client
+	}{
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Safari/537.36",
+			Client{"Chrome", "Windows", "Desktop"}},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Safari/537.36 Edg/118.0.2088.76",
+			Client{"Edge", "Windows", "Desktop"}},
+		{"Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:119.0) Gecko/20100101 Firefox/119.0",
+			Client{"Firefox", "Linux", "Desktop"}},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Safari/605.1.15",
+			Client{"Safari", "Mac OS", "Desktop"}},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1",
+			Client{"Safari", "iOS", "Mobile"}},
+		{"Mozilla/5.0 (iPad; CPU OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/118.0.5993.92 Mobile/15E148 Safari/604.1",
+			Client{"Chrome", "iOS", "Tablet"}},
+		{"Mozilla/5.0 (Linux; Android 13; SAMSUNG SM-S911B) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/23.0 Chrome/115.0.0.0 Mobile Safari/537.36",
+			Client{"Samsung Internet", "Android", "Mobile"}},
+		{"Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Safari/537.36",
+			Client{"Chrome", "Android", "Tablet"}},
+		{"Mozilla/5.0 (X11; CrOS x86_64 14541.0.0) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Safari/537.36",
+			Client{"Chrome", "Chrome OS", "Desktop"}},
+		{"Mozilla/5.0 (Windows NT 10.0; Trident/7.0; rv:11.0) like Gecko",
+			Client{"Internet Explorer", "Windows", "Desktop"}},
+		{"curl/8.4.0", Client{Other, Other, Other}},
+	}
+	for _, test := range tests {
+		if client := p.Parse(test.userAgent); *client != test.client {
+			t.Errorf("parsed %q as %+v, expected %+v", test.userAgent, *client, test.client)
+		}
+	}
+}
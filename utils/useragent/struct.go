@@ -0,0 +1,41 @@
+package useragent
+
+import (
+	"regexp"
+	"sync"
+	"time"
+)
+
+// BotClassifier detects crawlers, link preview fetchers and other automated
+// clients by their user agent
+type BotClassifier struct {
+	File string
+	// Treat requests without a user agent as bots
+	EmptyUserAgent bool
+
+	mx      *sync.RWMutex
+	pattern *regexp.Regexp
+	modTime time.Time
+}
+
+// Parser breaks user agents down into browser, operating system and device
+// class using the rules of a rules file
+type Parser struct {
+	File string
+
+	mx      *sync.RWMutex
+	rules   map[string][]*Rule
+	modTime time.Time
+}
+
+// Rule assigns Name to all user agents matching Pattern
+type Rule struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+type Client struct {
+	Browser string
+	OS      string
+	Device  string
+}